@@ -0,0 +1,25 @@
+// This package holds the dependencies shared by the whole application.
+package app
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"moviepin/config"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// App is built once at startup and handed to everything that needs the
+// database, logger, validator or settings.
+type App struct {
+	Config   config.Config
+	DB       *sql.DB
+	Logger   *slog.Logger
+	Validate *validator.Validate
+}
+
+// Returns a new App.
+func New(config config.Config, db *sql.DB, logger *slog.Logger, validate *validator.Validate) *App {
+	return &App{Config: config, DB: db, Logger: logger, Validate: validate}
+}
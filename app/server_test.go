@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"moviepin/config"
+	"moviepin/utils"
+)
+
+func TestServe(t *testing.T) {
+	t.Run("drains in-flight requests", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app := New(config.Default(), nil, utils.NewLogger(io.Discard, utils.LogFormatText), utils.NewValidator())
+
+		started := make(chan struct{})
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		served := make(chan error, 1)
+
+		go func() {
+			served <- app.Serve(ctx, listener, handler)
+		}()
+
+		responded := make(chan int, 1)
+
+		go func() {
+			res, err := http.Get("http://" + listener.Addr().String())
+			if err != nil {
+				responded <- 0
+				return
+			}
+
+			res.Body.Close()
+			responded <- res.StatusCode
+		}()
+
+		<-started
+		cancel()
+
+		if code := <-responded; code != http.StatusNoContent {
+			t.Errorf("in-flight request was not drained, got status %d", code)
+		}
+
+		if err := <-served; err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("drain deadline", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cfg := config.Default()
+		cfg.Server.ShutdownTimeout = 10 * time.Millisecond
+
+		app := New(cfg, nil, utils.NewLogger(io.Discard, utils.LogFormatText), utils.NewValidator())
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		served := make(chan error, 1)
+
+		go func() {
+			served <- app.Serve(ctx, listener, handler)
+		}()
+
+		go http.Get("http://" + listener.Addr().String())
+
+		<-started
+		cancel()
+
+		if err := <-served; err == nil {
+			t.Errorf("expected error when requests outlive the shutdown timeout")
+		}
+	})
+}
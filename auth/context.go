@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"context"
+
+	"moviepin/models"
+)
+
+type contextKey struct{}
+
+// Returns a copy of ctx carrying the authenticated user.
+func NewContext(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// Returns the authenticated user stored in ctx, if any.
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(contextKey{}).(*models.User)
+
+	return user, ok && user != nil
+}
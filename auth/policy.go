@@ -0,0 +1,78 @@
+package auth
+
+import "moviepin/models"
+
+// Roles a user can have. Every registered user starts as a member.
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleMember = "member"
+)
+
+// Permission is an operation a role can be granted.
+type Permission string
+
+const (
+	// Replace the whole movie catalog at once.
+	PermReplaceMovies Permission = "replace movies"
+
+	// Add, update and delete single movies.
+	PermWriteMovies Permission = "write movies"
+
+	// Write own reviews.
+	PermWriteReviews Permission = "write reviews"
+
+	// Update and delete reviews of other users.
+	PermModerateReviews Permission = "moderate reviews"
+
+	// Write own lists.
+	PermWriteLists Permission = "write lists"
+
+	// Set own watch statuses.
+	PermWriteWatchlist Permission = "write watchlist"
+
+	// Log own viewings in the diary.
+	PermWriteDiary Permission = "write diary"
+)
+
+// Policy decides which permissions each role is granted.
+type Policy struct {
+	grants map[string]map[Permission]bool
+}
+
+// Returns a policy with the default grants: members write reviews, lists,
+// their watchlist and diary, editors additionally manage single movies and
+// admins can do everything.
+func NewPolicy() *Policy {
+	member := []Permission{PermWriteReviews, PermWriteLists, PermWriteWatchlist, PermWriteDiary}
+	editor := append([]Permission{PermWriteMovies}, member...)
+	admin := append([]Permission{PermReplaceMovies, PermModerateReviews}, editor...)
+
+	p := &Policy{grants: make(map[string]map[Permission]bool)}
+
+	p.Grant(RoleMember, member...)
+	p.Grant(RoleEditor, editor...)
+	p.Grant(RoleAdmin, admin...)
+
+	return p
+}
+
+// Grants permissions to a role.
+func (p *Policy) Grant(role string, perms ...Permission) {
+	if p.grants[role] == nil {
+		p.grants[role] = make(map[Permission]bool)
+	}
+
+	for _, perm := range perms {
+		p.grants[role][perm] = true
+	}
+}
+
+// Reports whether the role of user is granted perm.
+func (p *Policy) Allows(user *models.User, perm Permission) bool {
+	if user == nil {
+		return false
+	}
+
+	return p.grants[user.Role][perm]
+}
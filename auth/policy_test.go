@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"testing"
+
+	"moviepin/models"
+)
+
+func TestPolicyAllows(t *testing.T) {
+	policy := NewPolicy()
+
+	tests := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{RoleMember, PermWriteReviews, true},
+		{RoleMember, PermWriteLists, true},
+		{RoleMember, PermWriteWatchlist, true},
+		{RoleMember, PermWriteDiary, true},
+		{RoleMember, PermWriteMovies, false},
+		{RoleMember, PermReplaceMovies, false},
+		{RoleMember, PermModerateReviews, false},
+		{RoleEditor, PermWriteMovies, true},
+		{RoleEditor, PermWriteReviews, true},
+		{RoleEditor, PermReplaceMovies, false},
+		{RoleAdmin, PermReplaceMovies, true},
+		{RoleAdmin, PermModerateReviews, true},
+		{RoleAdmin, PermWriteMovies, true},
+		{"unknown", PermWriteReviews, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.role+" "+string(test.perm), func(t *testing.T) {
+			got := policy.Allows(&models.User{Role: test.role}, test.perm)
+
+			if got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	t.Run("anonymous", func(t *testing.T) {
+		if policy.Allows(nil, PermWriteReviews) {
+			t.Errorf("anonymous user allowed to %s", PermWriteReviews)
+		}
+	})
+}
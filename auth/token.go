@@ -0,0 +1,34 @@
+// This package provides helpers to issue and check authentication tokens.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// SessionTTL is how long an issued token stays valid.
+const SessionTTL = 24 * time.Hour
+
+// Returns a new random token along with the hash to be stored for it.
+func NewToken() (string, string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return token, HashToken(token), nil
+}
+
+// Returns the hash under which a token is stored. Only hashes are persisted so
+// a leaked sessions table cannot be used to impersonate users.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
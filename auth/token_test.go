@@ -0,0 +1,27 @@
+package auth
+
+import "testing"
+
+func TestNewToken(t *testing.T) {
+	token, hash, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token == "" || hash == "" {
+		t.Fatalf("got empty token %q or hash %q", token, hash)
+	}
+
+	if got := HashToken(token); got != hash {
+		t.Errorf("got hash %s, want %s", got, hash)
+	}
+
+	other, _, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other == token {
+		t.Errorf("got same token twice: %s", token)
+	}
+}
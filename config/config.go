@@ -0,0 +1,321 @@
+// This package loads the settings the server is started with.
+//
+// Settings are read from, in increasing order of precedence:
+//
+//  1. built-in defaults,
+//  2. a YAML file named by -config or MOVIEPIN_CONFIG,
+//  3. MOVIEPIN_* environment variables,
+//  4. command-line flags.
+//
+// So a flag always wins, and a file only needs the settings it changes.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variable naming the config file, used when -config is not set.
+const FileEnv = "MOVIEPIN_CONFIG"
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Features FeaturesConfig `yaml:"features"`
+	Cache    CacheConfig    `yaml:"cache"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+type ServerConfig struct {
+	// Address the server listens on, like ":4545".
+	Addr string `yaml:"addr"`
+
+	// Longest time to read a whole request, including the body.
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+
+	// Longest time to write a response.
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+
+	// Longest time an idle keep-alive connection stays open.
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+
+	// Longest time a request may take, its database queries are canceled
+	// after that.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+
+	// Longest time in-flight requests get to finish when shutting down.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	// Connection string of the Postgres database.
+	URL string `yaml:"url"`
+
+	// Largest number of open connections, 0 means unlimited.
+	MaxOpenConns int `yaml:"max_open_conns"`
+
+	// Largest number of idle connections kept in the pool.
+	MaxIdleConns int `yaml:"max_idle_conns"`
+
+	// Longest time a connection is reused, 0 means forever.
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+}
+
+// Cache-Control header values of successful GET responses, by route. An
+// empty value sends no header.
+type CacheConfig struct {
+	// GET /movies.
+	Movies string `yaml:"movies"`
+
+	// GET /movies/{id}. Search and autocomplete have no validators and are
+	// never cached.
+	Movie string `yaml:"movie"`
+}
+
+type LogConfig struct {
+	// Format of log records, "text" for key=value lines or "json".
+	Format string `yaml:"format"`
+}
+
+type FeaturesConfig struct {
+	// Whether anyone can sign up through POST /users.
+	Registration bool `yaml:"registration"`
+}
+
+// Returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            ":4545",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			RequestTimeout:  25 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			URL:             "postgres://ranmerc@localhost:5432/moviepin?sslmode=disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Features: FeaturesConfig{
+			Registration: true,
+		},
+		Cache: CacheConfig{
+			// Clients keep copies but revalidate them with ETags, as
+			// movies change whenever an editor updates them.
+			Movies: "no-cache",
+			Movie:  "no-cache",
+		},
+		Log: LogConfig{
+			Format: "text",
+		},
+	}
+}
+
+// A setting that can be changed from the environment and the command line.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{"addr", "MOVIEPIN_ADDR", "address to listen on", setString(func(c *Config) *string { return &c.Server.Addr })},
+	{"read-timeout", "MOVIEPIN_READ_TIMEOUT", "longest time to read a request", setDuration(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{"write-timeout", "MOVIEPIN_WRITE_TIMEOUT", "longest time to write a response", setDuration(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{"idle-timeout", "MOVIEPIN_IDLE_TIMEOUT", "longest time to keep an idle connection", setDuration(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{"request-timeout", "MOVIEPIN_REQUEST_TIMEOUT", "longest time a request may take", setDuration(func(c *Config) *time.Duration { return &c.Server.RequestTimeout })},
+	{"shutdown-timeout", "MOVIEPIN_SHUTDOWN_TIMEOUT", "longest time to drain requests on shutdown", setDuration(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"database-url", "MOVIEPIN_DATABASE_URL", "Postgres connection string", setString(func(c *Config) *string { return &c.Database.URL })},
+	{"db-max-open-conns", "MOVIEPIN_DB_MAX_OPEN_CONNS", "largest number of open database connections", setInt(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{"db-max-idle-conns", "MOVIEPIN_DB_MAX_IDLE_CONNS", "largest number of idle database connections", setInt(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{"db-conn-max-lifetime", "MOVIEPIN_DB_CONN_MAX_LIFETIME", "longest time a database connection is reused", setDuration(func(c *Config) *time.Duration { return &c.Database.ConnMaxLifetime })},
+	{"cache-movies", "MOVIEPIN_CACHE_MOVIES", "Cache-Control of GET /movies", setString(func(c *Config) *string { return &c.Cache.Movies })},
+	{"cache-movie", "MOVIEPIN_CACHE_MOVIE", "Cache-Control of GET /movies/{id}", setString(func(c *Config) *string { return &c.Cache.Movie })},
+	{"log-format", "MOVIEPIN_LOG_FORMAT", "format of log records, text or json", setString(func(c *Config) *string { return &c.Log.Format })},
+	{"registration", "MOVIEPIN_REGISTRATION", "allow signing up through POST /users", setBool(func(c *Config) *bool { return &c.Features.Registration })},
+}
+
+// Loads the settings from the file, environment and command-line args, then
+// validates them. getenv is os.Getenv outside of tests.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fs := flag.NewFlagSet("moviepin", flag.ContinueOnError)
+
+	file := fs.String("config", "", "YAML config file, defaults to $"+FileEnv)
+
+	flagValues := make([]*string, len(settings))
+
+	for i, s := range settings {
+		flagValues[i] = fs.String(s.flag, "", s.usage+" ($"+s.env+")")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	config := Default()
+
+	if *file == "" {
+		*file = getenv(FileEnv)
+	}
+
+	if *file != "" {
+		if err := loadFile(&config, *file); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			if err := s.set(&config, value); err != nil {
+				return Config{}, fmt.Errorf("invalid %s: %w", s.env, err)
+			}
+		}
+	}
+
+	var err error
+
+	// Visit only walks flags that were set, so unset ones keep lower values.
+	fs.Visit(func(f *flag.Flag) {
+		for i, s := range settings {
+			if err == nil && f.Name == s.flag {
+				if setErr := s.set(&config, *flagValues[i]); setErr != nil {
+					err = fmt.Errorf("invalid -%s: %w", s.flag, setErr)
+				}
+			}
+		}
+	})
+
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err = config.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// Overrides config with the settings in the YAML file at path. Unknown keys
+// are rejected so that typos do not go unnoticed.
+func loadFile(config *Config, path string) error {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	if err = decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Returns an error listing every setting with an unusable value.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server address is required"))
+	}
+
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		errs = append(errs, errors.New("server timeouts must not be negative"))
+	}
+
+	if c.Server.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("server request timeout must be positive"))
+	}
+
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server shutdown timeout must be positive"))
+	}
+
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("database url is required"))
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database connection limits must not be negative"))
+	}
+
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("database max idle connections must not exceed max open connections"))
+	}
+
+	if c.Database.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("database connection lifetime must not be negative"))
+	}
+
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		errs = append(errs, fmt.Errorf("log format must be text or json, not %q", c.Log.Format))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func setString(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func setInt(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+
+		if err != nil {
+			return err
+		}
+
+		*field(c) = n
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+
+		if err != nil {
+			return err
+		}
+
+		*field(c) = b
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+
+		if err != nil {
+			return err
+		}
+
+		*field(c) = d
+		return nil
+	}
+}
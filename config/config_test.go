@@ -0,0 +1,166 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "moviepin.yaml")
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func getenv(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		config, err := Load(nil, getenv(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if config != Default() {
+			t.Errorf("wrong config, got %+v want %+v", config, Default())
+		}
+	})
+
+	t.Run("example", func(t *testing.T) {
+		// The example is what deployments start from, so it documents the
+		// defaults rather than drifting from them.
+		config, err := Load([]string{"-config", "../config.example.yaml"}, getenv(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if config != Default() {
+			t.Errorf("wrong config, got %+v want %+v", config, Default())
+		}
+	})
+
+	t.Run("precedence", func(t *testing.T) {
+		path := writeFile(t, `
+server:
+  addr: ":1000"
+  read_timeout: 3s
+database:
+  url: postgres://file
+  max_idle_conns: 5
+`)
+
+		env := map[string]string{
+			FileEnv:                 path,
+			"MOVIEPIN_ADDR":         ":2000",
+			"MOVIEPIN_DATABASE_URL": "postgres://env",
+		}
+
+		config, err := Load([]string{"-addr", ":3000"}, getenv(env))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if config.Server.Addr != ":3000" {
+			t.Errorf("flag should win over env and file, got %v", config.Server.Addr)
+		}
+
+		if config.Database.URL != "postgres://env" {
+			t.Errorf("env should win over file, got %v", config.Database.URL)
+		}
+
+		if config.Server.ReadTimeout != 3*time.Second || config.Database.MaxIdleConns != 5 {
+			t.Errorf("file should win over defaults, got %+v", config)
+		}
+
+		if config.Server.WriteTimeout != Default().Server.WriteTimeout {
+			t.Errorf("unset settings should keep defaults, got %v", config.Server.WriteTimeout)
+		}
+	})
+
+	t.Run("config flag wins over env", func(t *testing.T) {
+		path := writeFile(t, "features:\n  registration: false\n")
+
+		env := map[string]string{FileEnv: "/does/not/exist.yaml"}
+
+		config, err := Load([]string{"-config", path}, getenv(env))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if config.Features.Registration {
+			t.Errorf("registration should be disabled by file")
+		}
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+		want string
+	}{
+		{
+			name: "unknown file key",
+			file: "server:\n  adr: \":1\"\n",
+			want: "field adr not found",
+		},
+		{
+			name: "invalid env",
+			env:  map[string]string{"MOVIEPIN_READ_TIMEOUT": "soon"},
+			want: "invalid MOVIEPIN_READ_TIMEOUT",
+		},
+		{
+			name: "invalid flag",
+			args: []string{"-db-max-open-conns", "many"},
+			want: "invalid -db-max-open-conns",
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-port", "1"},
+			want: "flag provided but not defined",
+		},
+		{
+			name: "empty addr",
+			args: []string{"-addr", ""},
+			want: "server address is required",
+		},
+		{
+			name: "idle above open",
+			args: []string{"-db-max-open-conns", "2", "-db-max-idle-conns", "3"},
+			want: "must not exceed",
+		},
+		{
+			name: "unknown log format",
+			env:  map[string]string{"MOVIEPIN_LOG_FORMAT": "xml"},
+			want: "log format must be text or json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.env
+
+			if tt.file != "" {
+				env = map[string]string{FileEnv: writeFile(t, tt.file)}
+			}
+
+			_, err := Load(tt.args, getenv(env))
+
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("wrong error, got %v want %q", err, tt.want)
+			}
+		})
+	}
+}
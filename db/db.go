@@ -1,30 +1,32 @@
-// This package provides a global db connection variable.
+// This package opens connections to the database.
 package db
 
 import (
 	"database/sql"
 	"fmt"
-	"os"
+
+	"moviepin/config"
 
 	_ "github.com/lib/pq"
 )
 
-var DB *sql.DB
-
-func init() {
-	var err error
-
-	DB, err = sql.Open("postgres", "user=ranmerc dbname=moviepin sslmode=disable")
+// Opens a connection pool to the Postgres database described by config and
+// checks that the database is reachable.
+func Open(config config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", config.URL)
 
 	if err != nil {
-		fmt.Println("failed to open connection with the Database")
-		fmt.Println(err)
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to open connection with the database: %w", err)
 	}
 
-	if err = DB.Ping(); err != nil {
-		fmt.Println("failed to connect to Database")
-		fmt.Println(err)
-		os.Exit(1)
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+
+	return db, nil
 }
@@ -0,0 +1,239 @@
+// This package provides methods to interact with the diary database.
+package diary
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"moviepin/models"
+
+	"github.com/lib/pq"
+)
+
+type DiaryRepository interface {
+	GetEntries(ctx context.Context, userID string, from time.Time, to time.Time) ([]*models.DiaryEntry, error)
+	GetEntry(ctx context.Context, userID string, entryID string) (*models.DiaryEntry, error)
+	AddEntry(ctx context.Context, entry models.DiaryEntry) error
+	DeleteEntry(ctx context.Context, userID string, entryID string) error
+	GetStats(ctx context.Context, userID string, year int, limit int) (*models.DiaryStats, error)
+}
+
+var (
+	// Error returned when diary entry does not exist.
+	ErrNotExists = errors.New("diary entry does not exist")
+
+	// Error returned when the movie of an entry does not exist.
+	ErrMovieNotExists = errors.New("movie does not exist")
+)
+
+// Postgres error code for foreign key violations.
+const foreignKeyViolation = "23503"
+
+// Constraint tying an entry to its movie.
+const movieForeignKey = "diaryentries_movie_id_fkey"
+
+// Columns of an entry with its movie, in the order entryFields scans them.
+const entryColumns = `d.diary_entry_id, d.user_id, d.review_id, d.watched_on, d.rewatch, d.notes, d.created_at,
+	m.movie_id, m.title, m.release_date, m.genre, m.director, m.description, m.version, m.created_at, m.updated_at`
+
+// Movie columns the top lists of the stats can be grouped by.
+const (
+	genreColumn    = "genre"
+	directorColumn = "director"
+)
+
+type Diary struct {
+	db *sql.DB
+}
+
+func NewDiary(db *sql.DB) *Diary {
+	return &Diary{db: db}
+}
+
+// Returns pointers to the fields of entry and its movie, in the order of
+// entryColumns.
+func entryFields(entry *models.DiaryEntry) []any {
+	movie := entry.Movie
+
+	return []any{&entry.ID, &entry.UserID, &entry.ReviewID, &entry.WatchedOn, &entry.Rewatch, &entry.Notes, &entry.CreatedAt,
+		&movie.ID, &movie.Title, &movie.ReleaseDate, &movie.Genre, &movie.Director, &movie.Description, &movie.Version, &movie.CreatedAt, &movie.UpdatedAt}
+}
+
+// Returns the entries of a user watched from the day from up to, but not
+// including, the day to, with their movies, oldest first.
+func (d Diary) GetEntries(ctx context.Context, userID string, from time.Time, to time.Time) ([]*models.DiaryEntry, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT "+entryColumns+` FROM diaryentries d JOIN movies m ON m.movie_id = d.movie_id
+		WHERE d.user_id = $1 AND d.watched_on >= $2 AND d.watched_on < $3
+		ORDER BY d.watched_on, d.created_at, d.diary_entry_id;`, userID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := make([]*models.DiaryEntry, 0)
+
+	for rows.Next() {
+		entry := &models.DiaryEntry{Movie: &models.Movie{}}
+
+		if err := rows.Scan(entryFields(entry)...); err != nil {
+			return nil, err
+		}
+
+		entry.MovieID = entry.Movie.ID
+
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Returns particular entry of a user with its movie.
+func (d Diary) GetEntry(ctx context.Context, userID string, entryID string) (*models.DiaryEntry, error) {
+	row := d.db.QueryRowContext(ctx, "SELECT "+entryColumns+` FROM diaryentries d JOIN movies m ON m.movie_id = d.movie_id
+		WHERE d.user_id = $1 AND d.diary_entry_id = $2;`, userID, entryID)
+
+	entry := &models.DiaryEntry{Movie: &models.Movie{}}
+
+	if err := row.Scan(entryFields(entry)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotExists
+		}
+
+		return nil, err
+	}
+
+	entry.MovieID = entry.Movie.ID
+
+	return entry, nil
+}
+
+// Adds entry to the diary of its user.
+func (d Diary) AddEntry(ctx context.Context, entry models.DiaryEntry) error {
+	_, err := d.db.ExecContext(ctx, "INSERT INTO diaryentries(diary_entry_id, user_id, movie_id, review_id, watched_on, rewatch, notes, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8);",
+		entry.ID, entry.UserID, entry.MovieID, entry.ReviewID, entry.WatchedOn.Format(time.DateOnly), entry.Rewatch, entry.Notes, entry.CreatedAt)
+
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation && pqErr.Constraint == movieForeignKey {
+		return ErrMovieNotExists
+	}
+
+	return err
+}
+
+// Removes an entry from the diary of a user.
+func (d Diary) DeleteEntry(ctx context.Context, userID string, entryID string) error {
+	result, err := d.db.ExecContext(ctx, "DELETE FROM diaryentries WHERE user_id = $1 AND diary_entry_id = $2;", userID, entryID)
+
+	if err != nil {
+		return err
+	}
+
+	num, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if num == 0 {
+		return ErrNotExists
+	}
+
+	return nil
+}
+
+// Returns the stats of the diary of a user: viewings per year and the genres
+// and directors with the most different movies watched, limit of each. A year
+// other than 0 restricts them all to that year. The figures are read in one
+// snapshot, so they agree with each other.
+func (d Diary) GetStats(ctx context.Context, userID string, year int, limit int) (*models.DiaryStats, error) {
+	tx, err := d.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	stats := &models.DiaryStats{}
+
+	if stats.FilmsPerYear, err = filmsPerYear(ctx, tx, userID, year); err != nil {
+		return nil, err
+	}
+
+	if stats.TopGenres, err = topFilmCounts(ctx, tx, genreColumn, userID, year, limit); err != nil {
+		return nil, err
+	}
+
+	if stats.TopDirectors, err = topFilmCounts(ctx, tx, directorColumn, userID, year, limit); err != nil {
+		return nil, err
+	}
+
+	return stats, tx.Commit()
+}
+
+// Returns the viewings of a user per year, oldest year first.
+func filmsPerYear(ctx context.Context, tx *sql.Tx, userID string, year int) ([]*models.YearStats, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT EXTRACT(YEAR FROM watched_on)::int AS year,
+		COUNT(DISTINCT movie_id), COUNT(*), COUNT(*) FILTER (WHERE rewatch)
+		FROM diaryentries WHERE user_id = $1 AND ($2 = 0 OR EXTRACT(YEAR FROM watched_on) = $2)
+		GROUP BY year ORDER BY year;`, userID, year)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	years := make([]*models.YearStats, 0)
+
+	for rows.Next() {
+		stats := &models.YearStats{}
+
+		if err := rows.Scan(&stats.Year, &stats.Films, &stats.Entries, &stats.Rewatches); err != nil {
+			return nil, err
+		}
+
+		years = append(years, stats)
+	}
+
+	return years, rows.Err()
+}
+
+// Returns the values of a movie column with the most different movies a
+// user watched, most first and ties by name. Movies without a value are left
+// out. Column must be one of the column constants, never user input.
+func topFilmCounts(ctx context.Context, tx *sql.Tx, column string, userID string, year int, limit int) ([]*models.FilmCount, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT m.`+column+`, COUNT(DISTINCT d.movie_id) AS films
+		FROM diaryentries d JOIN movies m ON m.movie_id = d.movie_id
+		WHERE d.user_id = $1 AND ($2 = 0 OR EXTRACT(YEAR FROM d.watched_on) = $2) AND COALESCE(m.`+column+`, '') <> ''
+		GROUP BY m.`+column+` ORDER BY films DESC, m.`+column+` LIMIT $3;`, userID, year, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := make([]*models.FilmCount, 0)
+
+	for rows.Next() {
+		count := &models.FilmCount{}
+
+		if err := rows.Scan(&count.Name, &count.Films); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,444 @@
+// This package provides methods to interact with the lists database.
+package lists
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"moviepin/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ListsRepository interface {
+	GetLists(ctx context.Context, userID string, publicOnly bool) ([]*models.List, error)
+	GetList(ctx context.Context, listID string) (*models.List, error)
+	GetListBySlug(ctx context.Context, username string, slug string) (*models.List, error)
+	GetListItems(ctx context.Context, listID string) ([]*models.ListItem, error)
+	AddList(ctx context.Context, list models.List) error
+	UpdateList(ctx context.Context, list models.List) error
+	DeleteList(ctx context.Context, listID string) error
+	AddListItem(ctx context.Context, item models.ListItem) (int, error)
+	DeleteListItem(ctx context.Context, listID string, itemID string) error
+	MoveListItem(ctx context.Context, listID string, itemID string, move models.ListItemMove) (int, error)
+	SetListOrder(ctx context.Context, listID string, itemIDs []uuid.UUID) error
+}
+
+var (
+	// Error returned when list does not exist.
+	ErrNotExists = errors.New("list does not exist")
+
+	// Error returned when list item does not exist.
+	ErrItemNotExists = errors.New("list item does not exist")
+
+	// Error returned when the movie is already in the list.
+	ErrDuplicateMovie = errors.New("movie is already in the list")
+
+	// Error returned when the owner of a list has another list with its slug.
+	ErrDuplicateSlug = errors.New("slug is already used by another list")
+)
+
+// Postgres error code for unique constraint violations.
+const uniqueViolation = "23505"
+
+// Constraint keeping a movie from being in a list twice.
+const movieUniqueConstraint = "listitems_list_id_movie_id_key"
+
+// Constraint keeping slugs of the lists of a user apart.
+const slugUniqueConstraint = "lists_user_id_slug_key"
+
+// Columns of a list, in the order listFields scans them.
+const listColumns = "list_id, user_id, title, description, visibility, slug, created_at, updated_at"
+
+type Lists struct {
+	db *sql.DB
+}
+
+func NewList(db *sql.DB) *Lists {
+	return &Lists{db: db}
+}
+
+// Returns pointers to the fields of list, in the order of listColumns.
+func listFields(list *models.List) []any {
+	return []any{&list.ID, &list.UserID, &list.Title, &list.Description, &list.Visibility, &list.Slug, &list.CreatedAt, &list.UpdatedAt}
+}
+
+// Returns slice of the lists of a user, newest first. With publicOnly only
+// the lists anyone can see are returned, all of them otherwise.
+func (l Lists) GetLists(ctx context.Context, userID string, publicOnly bool) ([]*models.List, error) {
+	rows, err := l.db.QueryContext(ctx, "SELECT "+listColumns+" FROM lists WHERE user_id = $1 AND (NOT $2 OR visibility = $3) ORDER BY created_at DESC;", userID, publicOnly, models.ListVisibilityPublic)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	lists := make([]*models.List, 0)
+
+	for rows.Next() {
+		list := &models.List{}
+
+		if err := rows.Scan(listFields(list)...); err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, list)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+// Returns particular list without its items.
+func (l Lists) GetList(ctx context.Context, listID string) (*models.List, error) {
+	row := l.db.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE list_id = $1;", listID)
+
+	list := &models.List{}
+
+	if err := row.Scan(listFields(list)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotExists
+		}
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Returns the list with slug of the user with username, whatever its
+// visibility.
+func (l Lists) GetListBySlug(ctx context.Context, username string, slug string) (*models.List, error) {
+	row := l.db.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE slug = $2 AND user_id = (SELECT user_id FROM users WHERE username = $1);", username, slug)
+
+	list := &models.List{}
+
+	if err := row.Scan(listFields(list)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotExists
+		}
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Returns the items of a list with their movies, ordered by position.
+func (l Lists) GetListItems(ctx context.Context, listID string) ([]*models.ListItem, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT li.list_item_id, li.list_id, li.position,
+		m.movie_id, m.title, m.release_date, m.genre, m.director, m.description, m.version, m.created_at, m.updated_at
+		FROM listitems li JOIN movies m ON m.movie_id = li.movie_id
+		WHERE li.list_id = $1 ORDER BY li.position;`, listID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := make([]*models.ListItem, 0)
+
+	for rows.Next() {
+		item := &models.ListItem{Movie: &models.Movie{}}
+		movie := item.Movie
+
+		if err := rows.Scan(&item.ID, &item.ListID, &item.Position, &movie.ID, &movie.Title, &movie.ReleaseDate, &movie.Genre, &movie.Director, &movie.Description, &movie.Version, &movie.CreatedAt, &movie.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		item.MovieID = movie.ID
+
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Adds list to the database.
+func (l Lists) AddList(ctx context.Context, list models.List) error {
+	if _, err := l.db.ExecContext(ctx, "INSERT INTO lists(list_id, user_id, title, description, visibility, slug, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8);", list.ID, list.UserID, list.Title, list.Description, list.Visibility, list.Slug, list.CreatedAt, list.UpdatedAt); err != nil {
+		return slugError(err)
+	}
+
+	return nil
+}
+
+// Updates the title, description, visibility and slug of a list.
+func (l Lists) UpdateList(ctx context.Context, list models.List) error {
+	result, err := l.db.ExecContext(ctx, "UPDATE lists SET title = $2, description = $3, visibility = $4, slug = $5, updated_at = $6 WHERE list_id = $1;", list.ID, list.Title, list.Description, list.Visibility, list.Slug, list.UpdatedAt)
+
+	if err != nil {
+		return slugError(err)
+	}
+
+	num, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if num == 0 {
+		return ErrNotExists
+	}
+
+	return nil
+}
+
+// Returns ErrDuplicateSlug when err is about the slug of a list being taken,
+// err otherwise.
+func slugError(err error) error {
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == slugUniqueConstraint {
+		return ErrDuplicateSlug
+	}
+
+	return err
+}
+
+// Deletes a list and its items from the database.
+func (l Lists) DeleteList(ctx context.Context, listID string) error {
+	result, err := l.db.ExecContext(ctx, "DELETE FROM lists WHERE list_id = $1;", listID)
+
+	if err != nil {
+		return err
+	}
+
+	num, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if num == 0 {
+		return ErrNotExists
+	}
+
+	return nil
+}
+
+// Appends item to the end of its list and returns the position it got.
+func (l Lists) AddListItem(ctx context.Context, item models.ListItem) (int, error) {
+	tx, err := l.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	if err = lockList(ctx, tx, item.ListID.String()); err != nil {
+		return 0, err
+	}
+
+	var position int
+
+	row := tx.QueryRowContext(ctx, `INSERT INTO listitems(list_item_id, list_id, movie_id, position)
+		SELECT $1, $2, $3, COALESCE(MAX(position) + 1, 0) FROM listitems WHERE list_id = $2
+		RETURNING position;`, item.ID, item.ListID, item.MovieID)
+
+	if err = row.Scan(&position); err != nil {
+		var pqErr *pq.Error
+
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == movieUniqueConstraint {
+			return 0, ErrDuplicateMovie
+		}
+
+		return 0, err
+	}
+
+	if err = touchList(ctx, tx, item.ListID.String()); err != nil {
+		return 0, err
+	}
+
+	return position, tx.Commit()
+}
+
+// Removes an item from a list. The listitems_close_gap trigger moves the
+// items after it up, so that positions stay contiguous.
+func (l Lists) DeleteListItem(ctx context.Context, listID string, itemID string) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = lockList(ctx, tx, listID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM listitems WHERE list_id = $1 AND list_item_id = $2;", listID, itemID)
+
+	if err != nil {
+		return err
+	}
+
+	num, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if num == 0 {
+		return ErrItemNotExists
+	}
+
+	if err = touchList(ctx, tx, listID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Moves an item of a list before or after another item or to an index, and
+// returns the position it ends up at. The other items shift to keep
+// positions contiguous.
+func (l Lists) MoveListItem(ctx context.Context, listID string, itemID string, move models.ListItemMove) (int, error) {
+	id, err := uuid.Parse(itemID)
+
+	if err != nil {
+		return 0, ErrItemNotExists
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	if err = lockList(ctx, tx, listID); err != nil {
+		return 0, err
+	}
+
+	ids, err := listItemIDs(ctx, tx, listID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	order, position, err := moveItem(ids, id, move)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err = setPositions(ctx, tx, listID, order); err != nil {
+		return 0, err
+	}
+
+	return position, tx.Commit()
+}
+
+// Puts the items of a list in the order of itemIDs, which must list each of
+// them exactly once.
+func (l Lists) SetListOrder(ctx context.Context, listID string, itemIDs []uuid.UUID) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = lockList(ctx, tx, listID); err != nil {
+		return err
+	}
+
+	current, err := listItemIDs(ctx, tx, listID)
+
+	if err != nil {
+		return err
+	}
+
+	if err = checkOrder(current, itemIDs); err != nil {
+		return err
+	}
+
+	if err = setPositions(ctx, tx, listID, itemIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Returns ids of the items of a list, ordered by position.
+func listItemIDs(ctx context.Context, tx *sql.Tx, listID string) ([]uuid.UUID, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT list_item_id FROM listitems WHERE list_id = $1 ORDER BY position;", listID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []uuid.UUID
+
+	for rows.Next() {
+		var id uuid.UUID
+
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Numbers the items of a list from 0 in the order of ids and marks the list
+// updated. Positions are only checked for uniqueness on commit, so items can
+// trade places.
+func setPositions(ctx context.Context, tx *sql.Tx, listID string, ids []uuid.UUID) error {
+	strs := make([]string, len(ids))
+
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE listitems SET position = ordered.position - 1
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS ordered(list_item_id, position)
+		WHERE listitems.list_id = $1 AND listitems.list_item_id = ordered.list_item_id;`, listID, pq.StringArray(strs))
+
+	if err != nil {
+		return err
+	}
+
+	return touchList(ctx, tx, listID)
+}
+
+// Locks the row of a list until tx ends, so that changes to its items are
+// made one at a time. Fails with ErrNotExists if there is no such list.
+func lockList(ctx context.Context, tx *sql.Tx, listID string) error {
+	var id string
+
+	err := tx.QueryRowContext(ctx, "SELECT list_id FROM lists WHERE list_id = $1 FOR UPDATE;", listID).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		return ErrNotExists
+	}
+
+	return err
+}
+
+// Sets the update time of a list to now.
+func touchList(ctx context.Context, tx *sql.Tx, listID string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE lists SET updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC' WHERE list_id = $1;", listID)
+
+	return err
+}
@@ -0,0 +1,87 @@
+package lists
+
+import (
+	"errors"
+	"slices"
+
+	"moviepin/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// Error returned when the item to move next to is not in the list.
+	ErrAnchorNotExists = errors.New("item to move next to is not in the list")
+
+	// Error returned when the index to move to is past the end of the list.
+	ErrInvalidPosition = errors.New("index is past the end of the list")
+
+	// Error returned when a new order is not a permutation of the items.
+	ErrOrderMismatch = errors.New("order must list every item of the list exactly once")
+)
+
+// Returns ids, ordered by position, with itemID moved as move says and the
+// position it ends up at.
+func moveItem(ids []uuid.UUID, itemID uuid.UUID, move models.ListItemMove) ([]uuid.UUID, int, error) {
+	from := slices.Index(ids, itemID)
+
+	if from == -1 {
+		return nil, 0, ErrItemNotExists
+	}
+
+	rest := slices.Delete(slices.Clone(ids), from, from+1)
+
+	var to int
+
+	switch {
+	case move.Index != nil:
+		to = *move.Index
+
+		if to < 0 || to > len(rest) {
+			return nil, 0, ErrInvalidPosition
+		}
+	case move.Before != nil:
+		to = slices.Index(rest, *move.Before)
+
+		if to == -1 {
+			return nil, 0, ErrAnchorNotExists
+		}
+	case move.After != nil:
+		to = slices.Index(rest, *move.After)
+
+		if to == -1 {
+			return nil, 0, ErrAnchorNotExists
+		}
+
+		to++
+	default:
+		return nil, 0, ErrInvalidPosition
+	}
+
+	return slices.Insert(rest, to, itemID), to, nil
+}
+
+// Checks that order lists every id of current exactly once.
+func checkOrder(current []uuid.UUID, order []uuid.UUID) error {
+	if len(order) != len(current) {
+		return ErrOrderMismatch
+	}
+
+	seen := make(map[uuid.UUID]bool, len(order))
+
+	for _, id := range order {
+		if seen[id] {
+			return ErrOrderMismatch
+		}
+
+		seen[id] = true
+	}
+
+	for _, id := range current {
+		if !seen[id] {
+			return ErrOrderMismatch
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package lists
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"moviepin/models"
+
+	"github.com/google/uuid"
+)
+
+func TestMoveItem(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	ids := []uuid.UUID{a, b, c, d}
+
+	index := func(i int) *int { return &i }
+
+	tests := []struct {
+		name     string
+		itemID   uuid.UUID
+		move     models.ListItemMove
+		want     []uuid.UUID
+		position int
+		wantErr  error
+	}{
+		{name: "before first", itemID: c, move: models.ListItemMove{Before: &a}, want: []uuid.UUID{c, a, b, d}, position: 0},
+		{name: "before later", itemID: a, move: models.ListItemMove{Before: &d}, want: []uuid.UUID{b, c, a, d}, position: 2},
+		{name: "after last", itemID: b, move: models.ListItemMove{After: &d}, want: []uuid.UUID{a, c, d, b}, position: 3},
+		{name: "after earlier", itemID: d, move: models.ListItemMove{After: &a}, want: []uuid.UUID{a, d, b, c}, position: 1},
+		{name: "to index", itemID: a, move: models.ListItemMove{Index: index(2)}, want: []uuid.UUID{b, c, a, d}, position: 2},
+		{name: "to end", itemID: a, move: models.ListItemMove{Index: index(3)}, want: []uuid.UUID{b, c, d, a}, position: 3},
+		{name: "in place", itemID: b, move: models.ListItemMove{Index: index(1)}, want: ids, position: 1},
+		{name: "past end", itemID: a, move: models.ListItemMove{Index: index(4)}, wantErr: ErrInvalidPosition},
+		{name: "negative index", itemID: a, move: models.ListItemMove{Index: index(-1)}, wantErr: ErrInvalidPosition},
+		{name: "next to itself", itemID: a, move: models.ListItemMove{After: &a}, wantErr: ErrAnchorNotExists},
+		{name: "unknown anchor", itemID: a, move: models.ListItemMove{Before: &uuid.Nil}, wantErr: ErrAnchorNotExists},
+		{name: "unknown item", itemID: uuid.New(), move: models.ListItemMove{Index: index(0)}, wantErr: ErrItemNotExists},
+		{name: "nowhere", itemID: a, wantErr: ErrInvalidPosition},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, position, err := moveItem(ids, test.itemID, test.move)
+
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v want %v", err, test.wantErr)
+			}
+
+			if test.wantErr != nil {
+				return
+			}
+
+			if !slices.Equal(got, test.want) || position != test.position {
+				t.Errorf("got %v at %d want %v at %d", got, position, test.want, test.position)
+			}
+		})
+	}
+
+	if !slices.Equal(ids, []uuid.UUID{a, b, c, d}) {
+		t.Errorf("ids were changed, got %v", ids)
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	current := []uuid.UUID{a, b, c}
+
+	tests := []struct {
+		name    string
+		order   []uuid.UUID
+		wantErr error
+	}{
+		{name: "same order", order: []uuid.UUID{a, b, c}},
+		{name: "new order", order: []uuid.UUID{c, a, b}},
+		{name: "missing item", order: []uuid.UUID{c, a}, wantErr: ErrOrderMismatch},
+		{name: "repeated item", order: []uuid.UUID{c, a, a}, wantErr: ErrOrderMismatch},
+		{name: "unknown item", order: []uuid.UUID{c, a, uuid.New()}, wantErr: ErrOrderMismatch},
+		{name: "extra item", order: []uuid.UUID{c, a, b, uuid.New()}, wantErr: ErrOrderMismatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := checkOrder(current, test.order); !errors.Is(err, test.wantErr) {
+				t.Errorf("got error %v want %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS Sessions;
+
+DROP INDEX IF EXISTS users_email_key;
+
+DROP INDEX IF EXISTS users_username_key;
@@ -0,0 +1,10 @@
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON Users (username);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON Users (email);
+
+CREATE TABLE IF NOT EXISTS Sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE Users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'editor', 'member'));
@@ -0,0 +1 @@
+ALTER TABLE Movies DROP COLUMN IF EXISTS version;
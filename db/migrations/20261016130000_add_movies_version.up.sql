@@ -0,0 +1,2 @@
+-- Incremented on every update, so writers can detect concurrent changes.
+ALTER TABLE Movies ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,18 @@
+ALTER TABLE ListItems
+    DROP CONSTRAINT IF EXISTS listitems_list_id_movie_id_key,
+    DROP CONSTRAINT IF EXISTS listitems_movie_id_fkey,
+    ADD CONSTRAINT listitems_movie_id_fkey FOREIGN KEY (movie_id) REFERENCES Movies(movie_id),
+    DROP CONSTRAINT IF EXISTS listitems_list_id_fkey,
+    ADD CONSTRAINT listitems_list_id_fkey FOREIGN KEY (list_id) REFERENCES Lists(list_id),
+    ALTER COLUMN position DROP NOT NULL,
+    ALTER COLUMN movie_id DROP NOT NULL,
+    ALTER COLUMN list_id DROP NOT NULL;
+
+DROP INDEX IF EXISTS lists_user_id_idx;
+
+ALTER TABLE Lists
+    DROP CONSTRAINT IF EXISTS lists_user_id_fkey,
+    ADD CONSTRAINT lists_user_id_fkey FOREIGN KEY (user_id) REFERENCES Users(user_id),
+    ALTER COLUMN description DROP DEFAULT,
+    ALTER COLUMN title DROP NOT NULL,
+    ALTER COLUMN user_id DROP NOT NULL;
@@ -0,0 +1,20 @@
+-- Lists were created without constraints, nothing wrote to them yet.
+ALTER TABLE Lists
+    ALTER COLUMN user_id SET NOT NULL,
+    ALTER COLUMN title SET NOT NULL,
+    ALTER COLUMN description SET DEFAULT '',
+    DROP CONSTRAINT IF EXISTS lists_user_id_fkey,
+    ADD CONSTRAINT lists_user_id_fkey FOREIGN KEY (user_id) REFERENCES Users(user_id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS lists_user_id_idx ON Lists (user_id);
+
+-- A movie is in a list at most once and leaves it with the list or movie.
+ALTER TABLE ListItems
+    ALTER COLUMN list_id SET NOT NULL,
+    ALTER COLUMN movie_id SET NOT NULL,
+    ALTER COLUMN position SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS listitems_list_id_fkey,
+    ADD CONSTRAINT listitems_list_id_fkey FOREIGN KEY (list_id) REFERENCES Lists(list_id) ON DELETE CASCADE,
+    DROP CONSTRAINT IF EXISTS listitems_movie_id_fkey,
+    ADD CONSTRAINT listitems_movie_id_fkey FOREIGN KEY (movie_id) REFERENCES Movies(movie_id) ON DELETE CASCADE,
+    ADD CONSTRAINT listitems_list_id_movie_id_key UNIQUE (list_id, movie_id);
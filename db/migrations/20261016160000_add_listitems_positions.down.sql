@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS listitems_close_gap ON ListItems;
+
+DROP FUNCTION IF EXISTS listitems_close_gap();
+
+ALTER TABLE ListItems DROP CONSTRAINT IF EXISTS listitems_list_id_position_key;
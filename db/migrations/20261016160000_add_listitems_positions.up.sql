@@ -0,0 +1,27 @@
+-- Renumbers positions from 0 in every list, closing the gaps of deleted items.
+UPDATE ListItems SET position = renumbered.position
+FROM (
+    SELECT list_item_id, ROW_NUMBER() OVER (PARTITION BY list_id ORDER BY position, list_item_id) - 1 AS position
+    FROM ListItems
+) AS renumbered
+WHERE ListItems.list_item_id = renumbered.list_item_id;
+
+-- Deferred, so that reordering can swap positions within a transaction.
+ALTER TABLE ListItems
+    ADD CONSTRAINT listitems_list_id_position_key UNIQUE (list_id, position) DEFERRABLE INITIALLY DEFERRED;
+
+-- Moves the items after a deleted one up, also when a deleted movie takes
+-- its items along. The list row is locked first, like every change to its
+-- items does.
+CREATE OR REPLACE FUNCTION listitems_close_gap() RETURNS trigger AS $$
+BEGIN
+    PERFORM 1 FROM Lists WHERE list_id = OLD.list_id FOR UPDATE;
+
+    UPDATE ListItems SET position = position - 1 WHERE list_id = OLD.list_id AND position > OLD.position;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER listitems_close_gap AFTER DELETE ON ListItems
+    FOR EACH ROW EXECUTE FUNCTION listitems_close_gap();
@@ -0,0 +1,13 @@
+-- What a user is doing about a movie, kept apart from their reviews. Only
+-- watched movies have the date they were watched on.
+CREATE TABLE IF NOT EXISTS WatchStatuses (
+    user_id UUID NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+    movie_id UUID NOT NULL REFERENCES Movies(movie_id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('want_to_watch', 'watching', 'watched')),
+    watched_at DATE CHECK ((status = 'watched') = (watched_at IS NOT NULL)),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, movie_id)
+);
+
+-- Watchlists are read page by page, most recently changed first.
+CREATE INDEX IF NOT EXISTS watchstatuses_user_id_updated_at_idx ON WatchStatuses (user_id, updated_at DESC, movie_id DESC);
@@ -0,0 +1,15 @@
+-- Every viewing of a movie a user logs, possibly many per movie. An entry
+-- keeps its date and notes when the review it links to is deleted.
+CREATE TABLE IF NOT EXISTS DiaryEntries (
+    diary_entry_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+    movie_id UUID NOT NULL REFERENCES Movies(movie_id) ON DELETE CASCADE,
+    review_id UUID REFERENCES Reviews(review_id) ON DELETE SET NULL,
+    watched_on DATE NOT NULL,
+    rewatch BOOLEAN NOT NULL DEFAULT FALSE,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Diaries are read a month or a year at a time.
+CREATE INDEX IF NOT EXISTS diaryentries_user_id_watched_on_idx ON DiaryEntries (user_id, watched_on);
@@ -0,0 +1,4 @@
+ALTER TABLE Lists
+    DROP CONSTRAINT IF EXISTS lists_user_id_slug_key,
+    DROP COLUMN IF EXISTS slug,
+    DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,24 @@
+-- Lists were readable by anyone until now, so the existing ones stay public.
+-- New lists are private unless their owner says otherwise.
+ALTER TABLE Lists
+    ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('private', 'unlisted', 'public')),
+    ADD COLUMN slug TEXT;
+
+ALTER TABLE Lists ALTER COLUMN visibility SET DEFAULT 'private';
+
+-- Slugs of existing lists come from their titles. When a user has several
+-- lists with the same slug, all but the oldest get the start of their id.
+UPDATE Lists SET slug = CASE WHEN slugged.n = 1 THEN slugged.base ELSE slugged.base || '-' || LEFT(slugged.list_id::text, 8) END
+FROM (
+    SELECT list_id, base, ROW_NUMBER() OVER (PARTITION BY user_id, base ORDER BY created_at, list_id) AS n
+    FROM (
+        SELECT list_id, user_id, created_at,
+            COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(regexp_replace(lower(title), '[^a-z0-9]+', '-', 'g'), 90)), ''), 'list') AS base
+        FROM Lists
+    ) AS based
+) AS slugged
+WHERE Lists.list_id = slugged.list_id;
+
+ALTER TABLE Lists
+    ALTER COLUMN slug SET NOT NULL,
+    ADD CONSTRAINT lists_user_id_slug_key UNIQUE (user_id, slug);
@@ -0,0 +1,3 @@
+ALTER TABLE Reviews
+    DROP CONSTRAINT IF EXISTS reviews_rating_check,
+    ALTER COLUMN rating TYPE DOUBLE PRECISION;
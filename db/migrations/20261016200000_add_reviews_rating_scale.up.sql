@@ -0,0 +1,6 @@
+-- Ratings are whole stars from 1 to 5. The column was created as a double,
+-- so ratings written before are rounded onto the scale.
+ALTER TABLE Reviews
+    ALTER COLUMN rating TYPE INTEGER USING LEAST(5, GREATEST(1, ROUND(rating)))::INTEGER,
+    DROP CONSTRAINT IF EXISTS reviews_rating_check,
+    ADD CONSTRAINT reviews_rating_check CHECK (rating BETWEEN 1 AND 5);
@@ -0,0 +1,156 @@
+package movies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"moviepin/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Codes describing why a movie of a batch could not be added.
+const (
+	// The movie id is already taken.
+	CodeDuplicateKey = "duplicate_key"
+
+	// The movie breaks a database constraint.
+	CodeConstraint = "constraint"
+
+	// The database failed for reasons unrelated to the movie.
+	CodeInternal = "internal"
+)
+
+// Movies inserted per statement, Postgres allows 65535 parameters and every
+// movie takes six.
+const insertChunkSize = 1000
+
+var uuidPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// Adds all movies in one transaction with multi-row inserts and returns their
+// ids in order. Either every movie is added or none is, a movie the database
+// rejects is reported as a *RowError when it can be told apart.
+func (m Movies) AddMovies(ctx context.Context, movies []models.Movie) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, len(movies))
+
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	if err := checkDuplicateIDs(ids); err != nil {
+		return nil, err
+	}
+
+	// Generated here rather than by the database, so ids line up with the
+	// movies without relying on the order of RETURNING.
+	for i := range ids {
+		if ids[i] == uuid.Nil {
+			ids[i] = uuid.New()
+		}
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	for start := 0; start < len(movies); start += insertChunkSize {
+		end := min(start+insertChunkSize, len(movies))
+
+		statement, args := buildInsertMovies(movies[start:end], ids[start:end])
+
+		if _, err = tx.ExecContext(ctx, statement, args...); err != nil {
+			return nil, insertError(err, ids)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Returns a statement inserting movies with ids, along with its arguments.
+func buildInsertMovies(movies []models.Movie, ids []uuid.UUID) (string, []any) {
+	var sb strings.Builder
+
+	sb.WriteString("INSERT INTO movies(movie_id, title, release_date, genre, director, description) VALUES ")
+
+	args := make([]any, 0, len(movies)*6)
+
+	for i, movie := range movies {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+
+		n := len(args)
+		fmt.Fprintf(&sb, "($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6)
+
+		args = append(args, ids[i], movie.Title, movie.ReleaseDate, movie.Genre, movie.Director, movie.Description)
+	}
+
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+
+// Wraps err of a multi-row insert into a *RowError when the row can be found.
+// Postgres does not say which row failed, but the detail of most constraint
+// errors quotes the key or the failing row, which starts with the id.
+func insertError(err error, ids []uuid.UUID) error {
+	var pqErr *pq.Error
+
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	id, parseErr := uuid.Parse(uuidPattern.FindString(pqErr.Detail))
+
+	if parseErr != nil {
+		return err
+	}
+
+	for i := range ids {
+		if ids[i] == id {
+			field := columnFields[pqErr.Column]
+
+			if field == "" {
+				field = columnFields[pqErr.Constraint]
+			}
+
+			return &RowError{Index: i, Field: field, Code: pqErr.Code.Name(), Err: err}
+		}
+	}
+
+	return err
+}
+
+// Returns a code and message describing why a movie could not be added. The
+// messages are fixed, as they are shown to clients and the database text
+// names tables and constraints.
+func DescribeFailure(err error) (string, string) {
+	var pqErr *pq.Error
+
+	switch {
+	case errors.Is(err, ErrDuplicateID):
+		return CodeDuplicateKey, ErrDuplicateID.Error()
+	case !errors.As(err, &pqErr):
+		return CodeInternal, "failed to add movie"
+	case pqErr.Code.Name() == "unique_violation":
+		return CodeDuplicateKey, "a movie with this id already exists"
+	case pqErr.Code.Class() == "23":
+		return CodeConstraint, "movie violates a database constraint"
+	case pqErr.Code.Class() == "22":
+		return CodeConstraint, "movie has a value the database does not accept"
+	}
+
+	return CodeInternal, "failed to add movie"
+}
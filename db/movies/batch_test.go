@@ -0,0 +1,95 @@
+package movies
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"moviepin/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestBuildInsertMovies(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	statement, args := buildInsertMovies([]models.Movie{{Title: "Up"}, {Title: "Heat"}}, ids)
+
+	if !strings.HasSuffix(statement, "VALUES ($1, $2, $3, $4, $5, $6), ($7, $8, $9, $10, $11, $12);") {
+		t.Errorf("wrong statement %q", statement)
+	}
+
+	if len(args) != 12 || args[0] != ids[0] || args[7] != "Heat" {
+		t.Errorf("wrong args %v", args)
+	}
+}
+
+func TestInsertError(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	t.Run("key in detail", func(t *testing.T) {
+		err := insertError(&pq.Error{
+			Code:       "23505",
+			Constraint: "movies_pkey",
+			Detail:     "Key (movie_id)=(" + ids[1].String() + ") already exists.",
+		}, ids)
+
+		var rowErr *RowError
+
+		if !errors.As(err, &rowErr) || rowErr.Index != 1 || rowErr.Field != "id" {
+			t.Errorf("wrong error, got %v", err)
+		}
+	})
+
+	t.Run("failing row in detail", func(t *testing.T) {
+		err := insertError(&pq.Error{
+			Code:   "23502",
+			Column: "title",
+			Detail: "Failing row contains (" + ids[0].String() + ", null, 2001-01-01, Drama, Someone, Something).",
+		}, ids)
+
+		var rowErr *RowError
+
+		if !errors.As(err, &rowErr) || rowErr.Index != 0 || rowErr.Field != "title" {
+			t.Errorf("wrong error, got %v", err)
+		}
+	})
+
+	t.Run("row unknown", func(t *testing.T) {
+		want := &pq.Error{Code: "57014"}
+
+		if err := insertError(want, ids); err != want {
+			t.Errorf("wrong error, got %v want %v", err, want)
+		}
+	})
+}
+
+func TestDescribeFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"duplicate in batch", &RowError{Err: ErrDuplicateID}, CodeDuplicateKey},
+		{"unique violation", &pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint "movies_pkey"`}, CodeDuplicateKey},
+		{"not null violation", &pq.Error{Code: "23502"}, CodeConstraint},
+		{"data exception", &pq.Error{Code: "22007"}, CodeConstraint},
+		{"other database error", &pq.Error{Code: "57014"}, CodeInternal},
+		{"other error", errors.New("connection refused"), CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, message := DescribeFailure(tt.err)
+
+			if code != tt.want {
+				t.Errorf("wrong code, got %v want %v", code, tt.want)
+			}
+
+			if strings.Contains(message, "pq:") || strings.Contains(message, "movies_pkey") {
+				t.Errorf("message leaks database text %q", message)
+			}
+		})
+	}
+}
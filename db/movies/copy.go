@@ -0,0 +1,116 @@
+package movies
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// RowError is returned when a single movie of a bulk write is rejected.
+type RowError struct {
+	// Position of the movie in the batch.
+	Index int
+
+	// JSON name of the field at fault, empty when it is not known.
+	Field string
+
+	// Postgres condition name of the failure, like "unique_violation".
+	Code string
+
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("movie %d: %v", e.Index, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Error returned when a batch has the same id more than once.
+var ErrDuplicateID = errors.New("duplicate movie id in batch")
+
+// Postgres names the failing line of a COPY in the error context, like
+// "COPY movies, line 42, column title: ...".
+var copyLinePattern = regexp.MustCompile(`^COPY \w+, line (\d+)`)
+
+// JSON names of the movies columns and constraints errors can point at.
+var columnFields = map[string]string{
+	"movie_id":     "id",
+	"movies_pkey":  "id",
+	"title":        "title",
+	"release_date": "release_date",
+	"genre":        "genre",
+	"director":     "director",
+	"description":  "description",
+}
+
+// Returns a *RowError for the first movie repeating an id of an earlier one.
+// Checked up front as it is cheap and names the row without a round trip.
+// Nil ids are left for the database to generate and never clash.
+func checkDuplicateIDs(ids []uuid.UUID) error {
+	seen := make(map[uuid.UUID]int, len(ids))
+
+	for i, id := range ids {
+		if id == uuid.Nil {
+			continue
+		}
+
+		if first, ok := seen[id]; ok {
+			return &RowError{
+				Index: i,
+				Field: "id",
+				Code:  "unique_violation",
+				Err:   fmt.Errorf("%w: same id as movie %d", ErrDuplicateID, first),
+			}
+		}
+
+		seen[id] = i
+	}
+
+	return nil
+}
+
+// Wraps err of a COPY into a *RowError naming the row Postgres rejected. When
+// Postgres does not name one, fallback is used if it is a row index. Only
+// data and constraint errors are about a row, others like a canceled query or
+// a broken connection are returned as they are.
+func copyError(err error, fallback int) error {
+	var pqErr *pq.Error
+
+	if !errors.As(err, &pqErr) || !isRowErrorClass(pqErr.Code.Class()) {
+		return err
+	}
+
+	index := fallback
+
+	if match := copyLinePattern.FindStringSubmatch(pqErr.Where); match != nil {
+		line, _ := strconv.Atoi(match[1])
+
+		// Lines count from one.
+		index = line - 1
+	}
+
+	if index < 0 {
+		return err
+	}
+
+	field := columnFields[pqErr.Column]
+
+	if field == "" {
+		field = columnFields[pqErr.Constraint]
+	}
+
+	return &RowError{Index: index, Field: field, Code: pqErr.Code.Name(), Err: err}
+}
+
+// Tells whether errors of class are caused by the data of a row, which holds
+// for data exceptions (22) and integrity constraint violations (23).
+func isRowErrorClass(class pq.ErrorClass) bool {
+	return class == "22" || class == "23"
+}
@@ -0,0 +1,105 @@
+package movies
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestCheckDuplicateIDs(t *testing.T) {
+	id := uuid.New()
+
+	ids := []uuid.UUID{id, uuid.Nil, uuid.New(), uuid.Nil, id}
+
+	var rowErr *RowError
+
+	if err := checkDuplicateIDs(ids); !errors.As(err, &rowErr) || !errors.Is(err, ErrDuplicateID) {
+		t.Fatalf("wrong error, got %v", err)
+	}
+
+	if rowErr.Index != 4 || rowErr.Field != "id" {
+		t.Errorf("wrong row error, got %+v", rowErr)
+	}
+
+	if err := checkDuplicateIDs(ids[:4]); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCopyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		fallback int
+		want     *RowError
+	}{
+		{
+			name:     "line named by postgres",
+			err:      &pq.Error{Code: "23502", Column: "title", Where: "COPY movies, line 42: \"...\""},
+			fallback: -1,
+			want:     &RowError{Index: 41, Field: "title", Code: "not_null_violation"},
+		},
+		{
+			name:     "constraint",
+			err:      &pq.Error{Code: "23505", Constraint: "movies_pkey", Where: "COPY movies, line 3"},
+			fallback: 7,
+			want:     &RowError{Index: 2, Field: "id", Code: "unique_violation"},
+		},
+		{
+			name:     "fallback row",
+			err:      &pq.Error{Code: "22007"},
+			fallback: 5,
+			want:     &RowError{Index: 5, Code: "invalid_datetime_format"},
+		},
+		{
+			name:     "query canceled",
+			err:      &pq.Error{Code: "57014"},
+			fallback: 5,
+		},
+		{
+			name:     "not a database error",
+			err:      context.DeadlineExceeded,
+			fallback: 5,
+		},
+		{
+			name:     "no row",
+			err:      &pq.Error{Code: "23503"},
+			fallback: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := copyError(tt.err, tt.fallback)
+
+			var rowErr *RowError
+
+			if !errors.As(err, &rowErr) {
+				if tt.want != nil {
+					t.Fatalf("wrong error, got %v want row error", err)
+				}
+
+				if err != tt.err {
+					t.Errorf("wrong error, got %v want %v", err, tt.err)
+				}
+
+				return
+			}
+
+			if tt.want == nil {
+				t.Fatalf("unexpected row error %+v", rowErr)
+			}
+
+			if rowErr.Index != tt.want.Index || rowErr.Field != tt.want.Field || rowErr.Code != tt.want.Code {
+				t.Errorf("wrong row error, got %+v want %+v", rowErr, tt.want)
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("row error does not wrap %v", tt.err)
+			}
+		})
+	}
+}
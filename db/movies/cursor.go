@@ -0,0 +1,56 @@
+package movies
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// Error returned when a page cursor cannot be decoded or was issued for a
+	// different sort order.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Position of the last movie of a page. Clients only ever see it encoded, so
+// its contents can change without breaking them.
+type cursor struct {
+	// Sort order the cursor was issued for.
+	Sort string `json:"s,omitempty"`
+
+	// Values of the sort fields of the last movie.
+	Values []string `json:"v,omitempty"`
+
+	// Id of the last movie, which breaks ties between equal sort values.
+	ID uuid.UUID `json:"id"`
+}
+
+// Returns opaque string form of a cursor.
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Returns cursor from its opaque string form, checking it belongs to sort.
+func decodeCursor(s string, sort []SortField) (cursor, error) {
+	var c cursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err = json.Unmarshal(b, &c); err != nil || c.ID == uuid.Nil {
+		return c, ErrInvalidCursor
+	}
+
+	if c.Sort != FormatSort(sort) || len(c.Values) != len(sort) {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
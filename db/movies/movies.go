@@ -2,28 +2,60 @@
 package movies
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"sync"
+	"fmt"
+	"strings"
 
 	"moviepin/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type MoviesRepository interface {
-	GetMovies() ([]*models.Movie, error)
-	GetMovie(id string) (*models.Movie, error)
-	AddMovie(movie models.Movie) error
-	UpdateMovie(id string, movie models.Movie) error
-	DeleteMovie(id string) error
-	ReplaceMovies(movies []*models.Movie) error
-	GetMovieRating(id string) (*models.MovieReview, error)
+	GetMovies(ctx context.Context) ([]*models.Movie, error)
+	GetMoviesPage(ctx context.Context, query MoviesQuery) (*models.MoviesPage, error)
+	GetMovie(ctx context.Context, id string) (*models.Movie, error)
+	AddMovie(ctx context.Context, movie models.Movie) (uuid.UUID, error)
+	AddMovies(ctx context.Context, movies []models.Movie) ([]uuid.UUID, error)
+	UpdateMovie(ctx context.Context, id string, movie models.Movie) (int, error)
+	DeleteMovie(ctx context.Context, id string, version int) error
+	ReplaceMovies(ctx context.Context, movies []*models.Movie) error
+	GetMovieRating(ctx context.Context, id string) (*models.MovieReview, error)
+	SearchMovies(ctx context.Context, query string, limit int) ([]*models.MovieSearchResult, error)
+	AutocompleteMovies(ctx context.Context, prefix string, limit int) ([]*models.MovieSuggestion, error)
 }
 
 var (
 	// Error returned when movie does not exist.
 	ErrNotExists = errors.New("movie does not exist")
+
+	// Error returned when a conditional write finds the movie at another
+	// version than expected.
+	ErrVersionMismatch = errors.New("movie version does not match")
+
+	// Error returned when deleting a movie users still refer to, alone or
+	// by leaving it out of a replace.
+	ErrMovieInUse = errors.New("movie is still in use")
 )
 
+// Describes which page of movies to return.
+type MoviesQuery struct {
+	// Maximum number of movies in the page.
+	Limit int
+
+	// Cursor returned along with the previous page, empty for the first page.
+	Cursor string
+
+	// Only movies matching the filter are returned.
+	Filter MoviesFilter
+
+	// Order of movies, ties and the empty sort are ordered by id.
+	Sort []SortField
+}
+
 type Movies struct {
 	db *sql.DB
 }
@@ -33,8 +65,8 @@ func NewMovie(db *sql.DB) *Movies {
 }
 
 // Returns slice of all movies present.
-func (m Movies) GetMovies() ([]*models.Movie, error) {
-	rows, err := m.db.Query("SELECT movie_id, title, release_date, genre, director, description FROM movies;")
+func (m Movies) GetMovies(ctx context.Context) ([]*models.Movie, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT "+movieColumns+" FROM movies;")
 
 	if err != nil {
 		return nil, err
@@ -42,28 +74,105 @@ func (m Movies) GetMovies() ([]*models.Movie, error) {
 
 	defer rows.Close()
 
+	return scanMovies(rows)
+}
+
+// Returns a page of movies matching the query. Pages are read with keyset
+// pagination, so they stay stable while movies are added or removed.
+func (m Movies) GetMoviesPage(ctx context.Context, query MoviesQuery) (*models.MoviesPage, error) {
+	var c *cursor
+
+	if query.Cursor != "" {
+		decoded, err := decodeCursor(query.Cursor, query.Sort)
+
+		if err != nil {
+			return nil, err
+		}
+
+		c = &decoded
+	}
+
+	statement, args := buildMoviesPageQuery(query, c)
+
+	rows, err := m.db.QueryContext(ctx, statement, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	movies, err := scanMovies(rows)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.MoviesPage{Movies: movies}
+
+	if len(movies) > query.Limit {
+		page.Movies = movies[:query.Limit]
+		last := page.Movies[query.Limit-1]
+
+		next := cursor{Sort: FormatSort(query.Sort), ID: last.ID}
+
+		for _, field := range query.Sort {
+			next.Values = append(next.Values, sortValue(last, field.Field))
+		}
+
+		page.NextCursor = encodeCursor(next)
+	}
+
+	return page, nil
+}
+
+// Columns of a movie in the order movieFields scans them.
+const movieColumns = "movie_id, title, release_date, genre, director, description, version, created_at, updated_at"
+
+// Returns pointers to the fields of movie that movieColumns are scanned into.
+func movieFields(movie *models.Movie) []any {
+	return []any{&movie.ID, &movie.Title, &movie.ReleaseDate, &movie.Genre, &movie.Director, &movie.Description, &movie.Version, &movie.CreatedAt, &movie.UpdatedAt}
+}
+
+// Returns movies read from rows.
+func scanMovies(rows *sql.Rows) ([]*models.Movie, error) {
 	movies := make([]*models.Movie, 0)
 
 	for rows.Next() {
 		movie := &models.Movie{}
 
-		if err := rows.Scan(&movie.ID, &movie.Title, &movie.ReleaseDate, &movie.Genre, &movie.Director, &movie.Description); err != nil {
+		if err := rows.Scan(movieFields(movie)...); err != nil {
 			return nil, err
 		}
 
 		movies = append(movies, movie)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
 	return movies, nil
 }
 
-// Replaces movies collection with passed in collection
-func (m Movies) ReplaceMovies(movies []*models.Movie) error {
-	tx, err := m.db.Begin()
+// Replaces movies collection with passed in collection. Rows are streamed
+// with COPY into a staging table inside one transaction, so either all movies
+// are replaced or none are. Movies kept by id are updated in place, so
+// whatever users attached to them stays, and only movies left out are
+// deleted. A row the database rejects is reported as a *RowError, and a movie
+// left out that users still refer to as ErrMovieInUse.
+func (m Movies) ReplaceMovies(ctx context.Context, movies []*models.Movie) error {
+	ids := make([]uuid.UUID, len(movies))
+
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	if err := checkDuplicateIDs(ids); err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
 
 	if err != nil {
 		return err
@@ -71,45 +180,118 @@ func (m Movies) ReplaceMovies(movies []*models.Movie) error {
 
 	defer tx.Rollback()
 
-	_, err = tx.Exec("DELETE FROM movies")
+	if _, err = tx.ExecContext(ctx, "CREATE TEMP TABLE movies_replacement (LIKE movies INCLUDING DEFAULTS INCLUDING CONSTRAINTS) ON COMMIT DROP;"); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("movies_replacement", "movie_id", "title", "release_date", "genre", "director", "description"))
 
 	if err != nil {
 		return err
 	}
 
-	var wg sync.WaitGroup
+	defer stmt.Close()
 
-	for _, movie := range movies {
-		wg.Add(1)
+	for i, movie := range movies {
+		// Rows are buffered, so the error may belong to an earlier row.
+		if _, err = stmt.ExecContext(ctx, movie.ID, movie.Title, movie.ReleaseDate, movie.Genre, movie.Director, movie.Description); err != nil {
+			return copyError(err, i)
+		}
+	}
 
-		go func(movie *models.Movie) {
-			defer wg.Done()
+	// Flushes the buffered rows, most row errors surface here.
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return copyError(err, -1)
+	}
 
-			_, err := tx.Exec("INSERT INTO movies(movie_id, title, release_date, genre, director, description) VALUES($1, $2, $3, $4, $5, $6);", movie.ID, movie.Title, movie.ReleaseDate, movie.Genre, movie.Director, movie.Description)
+	if err = stmt.Close(); err != nil {
+		return err
+	}
 
-			if err != nil {
-				tx.Rollback()
-				return
-			}
-		}(movie)
+	// Temporary tables are never analyzed on their own, without statistics
+	// the planner would not hash join the catalog against the replacement.
+	if _, err = tx.ExecContext(ctx, "ANALYZE movies_replacement;"); err != nil {
+		return err
 	}
 
-	wg.Wait()
+	// Unchanged movies keep their version, so cached copies stay valid.
+	if _, err = tx.ExecContext(ctx, `INSERT INTO movies(movie_id, title, release_date, genre, director, description)
+		SELECT movie_id, title, release_date, genre, director, description FROM movies_replacement
+		ON CONFLICT (movie_id) DO UPDATE SET title=EXCLUDED.title, release_date=EXCLUDED.release_date, genre=EXCLUDED.genre,
+			director=EXCLUDED.director, description=EXCLUDED.description, version=movies.version+1, updated_at=CURRENT_TIMESTAMP
+		WHERE (movies.title, movies.release_date, movies.genre, movies.director, movies.description)
+			IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.release_date, EXCLUDED.genre, EXCLUDED.director, EXCLUDED.description);`); err != nil {
+		return err
+	}
 
-	if err = tx.Commit(); err != nil {
+	// Locking the movies to delete makes users wait with referring to them
+	// until the replace is done, so none is referred to unnoticed.
+	if _, err = tx.ExecContext(ctx, "SELECT COUNT(*) FROM (SELECT 1 FROM movies m WHERE "+leftOutCondition+" FOR UPDATE) removed;"); err != nil {
 		return err
 	}
 
-	return nil
+	var inUse uuid.UUID
+
+	err = tx.QueryRowContext(ctx, leftOutInUseQuery).Scan(&inUse)
+
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrMovieInUse, inUse)
+	}
+
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM movies m WHERE "+leftOutCondition+";"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Tables of user data referring to movies. Neither a delete nor a replace
+// removes a movie one of them refers to, as the data would either block it or
+// go with it.
+var movieReferences = []string{"reviews", "listitems", "watchstatuses", "diaryentries"}
+
+// Condition on movies m holding for those left out of the replacement. It
+// is an anti join, so it stays fast for large catalogs.
+const leftOutCondition = "NOT EXISTS (SELECT 1 FROM movies_replacement k WHERE k.movie_id = m.movie_id)"
+
+// Query returning a movie left out of the replacement that user data refers
+// to.
+var leftOutInUseQuery = buildMovieInUseQuery(movieReferences, leftOutCondition)
+
+// Query returning the movie with the id in $1 if user data refers to it.
+var deletedInUseQuery = buildMovieInUseQuery(movieReferences, "m.movie_id = $1")
+
+// Returns a query finding a movie m matching condition that a row of one of
+// tables refers to.
+func buildMovieInUseQuery(tables []string, condition string) string {
+	var sb strings.Builder
+
+	sb.WriteString("SELECT m.movie_id FROM movies m WHERE " + condition + " AND (")
+
+	for i, table := range tables {
+		if i > 0 {
+			sb.WriteString(" OR ")
+		}
+
+		fmt.Fprintf(&sb, "EXISTS (SELECT 1 FROM %s r WHERE r.movie_id = m.movie_id)", table)
+	}
+
+	sb.WriteString(") LIMIT 1;")
+
+	return sb.String()
 }
 
 // Returns particular movie.
-func (m Movies) GetMovie(id string) (*models.Movie, error) {
-	row := m.db.QueryRow("SELECT movie_id, title, release_date, genre, director, description FROM movies WHERE movie_id = $1;", id)
+func (m Movies) GetMovie(ctx context.Context, id string) (*models.Movie, error) {
+	row := m.db.QueryRowContext(ctx, "SELECT "+movieColumns+" FROM movies WHERE movie_id = $1;", id)
 
 	movie := &models.Movie{}
 
-	if err := row.Scan(&movie.ID, &movie.Title, &movie.ReleaseDate, &movie.Genre, &movie.Director, &movie.Description); err != nil {
+	if err := row.Scan(movieFields(movie)...); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotExists
 		}
@@ -120,61 +302,114 @@ func (m Movies) GetMovie(id string) (*models.Movie, error) {
 	return movie, nil
 }
 
-// Adds movie to the database.
-func (m Movies) AddMovie(newMovie models.Movie) error {
-	if _, err := m.db.Exec("INSERT INTO movies(movie_id, title, release_date, genre, director, description) VALUES($1, $2, $3, $4, $5, $6);", newMovie.ID, newMovie.Title, newMovie.ReleaseDate, newMovie.Genre, newMovie.Director, newMovie.Description); err != nil {
-		return err
+// Adds movie to the database and returns its id. The database generates the
+// id unless the movie already has one.
+func (m Movies) AddMovie(ctx context.Context, newMovie models.Movie) (uuid.UUID, error) {
+	var id any
+
+	if newMovie.ID != uuid.Nil {
+		id = newMovie.ID
+	}
+
+	row := m.db.QueryRowContext(ctx, "INSERT INTO movies(movie_id, title, release_date, genre, director, description) VALUES(COALESCE($1, uuid_generate_v4()), $2, $3, $4, $5, $6) RETURNING movie_id;", id, newMovie.Title, newMovie.ReleaseDate, newMovie.Genre, newMovie.Director, newMovie.Description)
+
+	var movieID uuid.UUID
+
+	if err := row.Scan(&movieID); err != nil {
+		return uuid.Nil, err
 	}
 
-	return nil
+	return movieID, nil
 }
 
-// Deletes a movie from the database.
-func (m Movies) DeleteMovie(id string) error {
-	result, err := m.db.Exec("DELETE FROM movies WHERE movie_id=$1;", id)
+// Deletes a movie. When version is not zero the movie is only deleted if it
+// is still at that version, ErrVersionMismatch is returned otherwise. Like a
+// replace, it never deletes a movie users still refer to and returns
+// ErrMovieInUse instead.
+func (m Movies) DeleteMovie(ctx context.Context, id string, version int) error {
+	tx, err := m.db.BeginTx(ctx, nil)
 
 	if err != nil {
 		return err
 	}
 
-	num, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	// Locking the movie makes users wait with referring to it until it is
+	// gone, so none is referred to unnoticed.
+	var current int
+
+	if err = tx.QueryRowContext(ctx, "SELECT version FROM movies WHERE movie_id=$1 FOR UPDATE;", id).Scan(&current); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotExists
+		}
 
-	if err != nil {
 		return err
 	}
 
-	if num == 0 {
-		return ErrNotExists
+	if version != 0 && current != version {
+		return ErrVersionMismatch
 	}
 
-	return nil
-}
+	var inUse uuid.UUID
 
-// Updates a movie in the database.
-func (m Movies) UpdateMovie(id string, movie models.Movie) error {
-	result, err := m.db.Exec("UPDATE movies SET movie_id=$1, title=$2, release_date=$3, genre=$4, director=$5, description=$6 WHERE movie_id=$7;", movie.ID, movie.Title, movie.ReleaseDate, movie.Genre, movie.Director, movie.Description, id)
+	err = tx.QueryRowContext(ctx, deletedInUseQuery, id).Scan(&inUse)
 
-	if err != nil {
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrMovieInUse, inUse)
+	}
+
+	if err != sql.ErrNoRows {
 		return err
 	}
 
-	num, err := result.RowsAffected()
+	if _, err = tx.ExecContext(ctx, "DELETE FROM movies WHERE movie_id=$1;", id); err != nil {
+		return err
+	}
 
-	if err != nil {
+	return tx.Commit()
+}
+
+// Updates a movie in the database and returns its new version. The id of a
+// movie never changes, so movie.ID is ignored. When movie.Version is not zero
+// the update is a compare-and-swap, it only happens if the movie is still at
+// that version and ErrVersionMismatch is returned otherwise.
+func (m Movies) UpdateMovie(ctx context.Context, id string, movie models.Movie) (int, error) {
+	row := m.db.QueryRowContext(ctx, "UPDATE movies SET title=$1, release_date=$2, genre=$3, director=$4, description=$5, version=version+1, updated_at=CURRENT_TIMESTAMP WHERE movie_id=$6 AND ($7::INTEGER = 0 OR version=$7) RETURNING version;", movie.Title, movie.ReleaseDate, movie.Genre, movie.Director, movie.Description, id, movie.Version)
+
+	var version int
+
+	if err := row.Scan(&version); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, m.missingOrChanged(ctx, id)
+		}
+
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// Tells why a conditional write matched no movie: ErrNotExists when the movie
+// is gone and ErrVersionMismatch when it moved on to another version.
+func (m Movies) missingOrChanged(ctx context.Context, id string) error {
+	var exists bool
+
+	if err := m.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM movies WHERE movie_id=$1);", id).Scan(&exists); err != nil {
 		return err
 	}
 
-	if num == 0 {
-		return ErrNotExists
+	if exists {
+		return ErrVersionMismatch
 	}
 
-	return nil
+	return ErrNotExists
 }
 
 // Returns movie details along with its rating.
-func (m Movies) GetMovieRating(id string) (*models.MovieReview, error) {
-	// Take a average of all the ratings for a movie.
-	row := m.db.QueryRow(`SELECT m.movie_id, m.title, m.release_date, m.genre, m.director, m.description, TRUNC(ROUND(AVG(r.rating)) / 2, 1) FROM movies m LEFT JOIN reviews r ON m.movie_id=r.movie_id WHERE m.movie_id=$1 GROUP BY m.movie_id;`, id)
+func (m Movies) GetMovieRating(ctx context.Context, id string) (*models.MovieReview, error) {
+	// Average of all the ratings of the movie, in stars to one decimal.
+	row := m.db.QueryRowContext(ctx, `SELECT m.movie_id, m.title, m.release_date, m.genre, m.director, m.description, TRUNC(AVG(r.rating), 1) FROM movies m LEFT JOIN reviews r ON m.movie_id=r.movie_id WHERE m.movie_id=$1 GROUP BY m.movie_id;`, id)
 
 	mr := &models.MovieReview{}
 
@@ -188,3 +423,80 @@ func (m Movies) GetMovieRating(id string) (*models.MovieReview, error) {
 
 	return mr, nil
 }
+
+// Returns movies matching a web search style query, most relevant first.
+// Title matches weigh more than director matches, which weigh more than
+// description matches. Highlights are HTML: the text of the movie is escaped, so the
+// <mark> tags around matches are the only markup in them.
+func (m Movies) SearchMovies(ctx context.Context, query string, limit int) ([]*models.MovieSearchResult, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT m.movie_id, m.title, m.release_date, m.genre, m.director, m.description, m.version, m.created_at, m.updated_at,
+		ts_rank(m.search_vector, q),
+		ts_headline('english', `+escapedHTML("m.title")+`, q, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+		ts_headline('english', `+escapedHTML("m.director")+`, q, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+		ts_headline('english', `+escapedHTML("m.description")+`, q, 'StartSel=<mark>, StopSel=</mark>, MinWords=15, MaxWords=35')
+		FROM movies m, websearch_to_tsquery('english', $1) q
+		WHERE m.search_vector @@ q
+		ORDER BY 10 DESC, m.movie_id
+		LIMIT $2;`, query, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results := make([]*models.MovieSearchResult, 0)
+
+	for rows.Next() {
+		r := &models.MovieSearchResult{}
+
+		fields := append(movieFields(&r.Movie), &r.Score, &r.Highlights.Title, &r.Highlights.Director, &r.Highlights.Description)
+
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Returns titles starting with prefix followed by titles resembling it, so
+// misspelled input like "shawshenk" still finds "The Shawshank Redemption".
+// Only id, title and year are read to keep lookups cheap.
+func (m Movies) AutocompleteMovies(ctx context.Context, prefix string, limit int) ([]*models.MovieSuggestion, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT movie_id, title, COALESCE(EXTRACT(YEAR FROM release_date)::INTEGER, 0)
+		FROM movies
+		WHERE title ILIKE $1 OR $2 <% title
+		ORDER BY title ILIKE $1 DESC, word_similarity($2, title) DESC, title
+		LIMIT $3;`, escapeLike(prefix)+"%", prefix, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	suggestions := make([]*models.MovieSuggestion, 0)
+
+	for rows.Next() {
+		suggestion := &models.MovieSuggestion{}
+
+		if err := rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Year); err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,257 @@
+package movies
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"moviepin/models"
+
+	"github.com/google/uuid"
+)
+
+// Records the statements run through it, without a database behind it.
+type recordingConn struct {
+	statements []string
+	args       [][]driver.Value
+
+	// Returns the rows of a query, none when nil.
+	rows func(query string) [][]driver.Value
+}
+
+func (c *recordingConn) Connect(context.Context) (driver.Conn, error) {
+	return c, nil
+}
+
+func (c *recordingConn) Driver() driver.Driver {
+	return nil
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{c, query}, nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	c.statements = append(c.statements, "COMMIT")
+	c.args = append(c.args, nil)
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	c.statements = append(c.statements, "ROLLBACK")
+	c.args = append(c.args, nil)
+	return nil
+}
+
+// Returns the arguments of the first statement starting with prefix.
+func (c *recordingConn) argsOf(prefix string) ([]driver.Value, bool) {
+	for i, statement := range c.statements {
+		if strings.HasPrefix(statement, prefix) {
+			return c.args[i], true
+		}
+	}
+
+	return nil, false
+}
+
+type recordingStmt struct {
+	conn  *recordingConn
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.statements = append(s.conn.statements, s.query)
+	s.conn.args = append(s.conn.args, args)
+	return driver.RowsAffected(0), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.Exec(args)
+
+	rows := &recordingRows{}
+
+	if s.conn.rows != nil {
+		rows.values = s.conn.rows(s.query)
+	}
+
+	return rows, nil
+}
+
+type recordingRows struct {
+	values [][]driver.Value
+}
+
+func (r *recordingRows) Columns() []string { return []string{"movie_id"} }
+func (r *recordingRows) Close() error      { return nil }
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+
+	return nil
+}
+
+func TestReplaceMovies(t *testing.T) {
+	kept := []*models.Movie{{ID: uuid.New(), Title: "Heat"}, {ID: uuid.New(), Title: "Up"}}
+
+	t.Run("movies kept by id keep their list items", func(t *testing.T) {
+		conn := &recordingConn{}
+
+		if err := NewMovie(sql.OpenDB(conn)).ReplaceMovies(context.Background(), kept); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		for _, statement := range conn.statements {
+			if strings.TrimSpace(statement) == "DELETE FROM movies;" {
+				t.Fatal("replace deletes every movie")
+			}
+
+			if strings.Contains(statement, "<> ALL(") {
+				t.Errorf("statement scans the kept ids for every movie %q", statement)
+			}
+		}
+
+		args, ok := conn.argsOf("DELETE FROM movies m WHERE " + leftOutCondition)
+
+		if !ok {
+			t.Fatalf("movies left out are not deleted, ran %q", conn.statements)
+		}
+
+		if len(args) != 0 {
+			t.Errorf("delete takes the kept ids as arguments %v, want them read from the replacement", args)
+		}
+
+		if _, ok := conn.argsOf("INSERT INTO movies(movie_id, title, release_date, genre, director, description)\n\t\tSELECT"); !ok {
+			t.Errorf("movies are not upserted, ran %q", conn.statements)
+		}
+
+		if last := conn.statements[len(conn.statements)-1]; last != "COMMIT" {
+			t.Errorf("replace not committed, last ran %q", last)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		table string
+	}{
+		{"movie left out with reviews", "reviews"},
+		{"movie left out in lists", "listitems"},
+		{"movie left out in watchlists", "watchstatuses"},
+		{"movie left out in diaries", "diaryentries"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inUse := uuid.New()
+
+			conn := &recordingConn{rows: func(query string) [][]driver.Value {
+				if strings.Contains(query, "FROM "+tt.table+" r") {
+					return [][]driver.Value{{inUse.String()}}
+				}
+
+				return nil
+			}}
+
+			err := NewMovie(sql.OpenDB(conn)).ReplaceMovies(context.Background(), kept)
+
+			if !errors.Is(err, ErrMovieInUse) || !strings.Contains(err.Error(), inUse.String()) {
+				t.Fatalf("wrong error, got %v want %v", err, ErrMovieInUse)
+			}
+
+			if _, ok := conn.argsOf("DELETE FROM movies"); ok {
+				t.Errorf("movies deleted although %v refer to one", tt.table)
+			}
+
+			if _, ok := conn.argsOf("COMMIT"); ok {
+				t.Error("replace committed")
+			}
+		})
+	}
+}
+
+func TestDeleteMovie(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name    string
+		version int
+		table   string
+		want    error
+	}{
+		{name: "unused movie", want: nil},
+		{name: "stale version", version: 2, want: ErrVersionMismatch},
+		{name: "movie with reviews", table: "reviews", want: ErrMovieInUse},
+		{name: "movie in lists", table: "listitems", want: ErrMovieInUse},
+		{name: "movie in watchlists", table: "watchstatuses", want: ErrMovieInUse},
+		{name: "movie in diaries", table: "diaryentries", want: ErrMovieInUse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &recordingConn{rows: func(query string) [][]driver.Value {
+				if strings.HasPrefix(query, "SELECT version FROM movies") {
+					return [][]driver.Value{{int64(3)}}
+				}
+
+				if tt.table != "" && strings.Contains(query, "FROM "+tt.table+" r") {
+					return [][]driver.Value{{id.String()}}
+				}
+
+				return nil
+			}}
+
+			err := NewMovie(sql.OpenDB(conn)).DeleteMovie(context.Background(), id.String(), tt.version)
+
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("wrong error, got %v want %v", err, tt.want)
+			}
+
+			_, deleted := conn.argsOf("DELETE FROM movies")
+
+			if deleted != (tt.want == nil) {
+				t.Errorf("wrong delete, deleted %v with error %v", deleted, err)
+			}
+		})
+	}
+}
+
+func TestSearchMoviesEscapesHighlights(t *testing.T) {
+	conn := &recordingConn{}
+
+	if _, err := NewMovie(sql.OpenDB(conn)).SearchMovies(context.Background(), "heat", 10); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(conn.statements) != 1 {
+		t.Fatalf("wrong statements %q", conn.statements)
+	}
+
+	for _, column := range []string{"m.title", "m.director", "m.description"} {
+		if strings.Contains(conn.statements[0], "ts_headline('english', "+column+",") {
+			t.Errorf("%v is highlighted without escaping", column)
+		}
+
+		if !strings.Contains(conn.statements[0], "ts_headline('english', "+escapedHTML(column)+",") {
+			t.Errorf("%v is not highlighted escaped", column)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package movies
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"moviepin/models"
+)
+
+var (
+	// Error returned when sort names an unknown field or repeats one.
+	ErrInvalidSort = errors.New("invalid sort")
+)
+
+// Columns movies can be sorted by. Only these names ever reach ORDER BY.
+var sortColumns = map[string]string{
+	"title":        "title",
+	"release_date": "release_date",
+	"genre":        "genre",
+	"director":     "director",
+}
+
+// Narrows down the movies returned. Zero values do not filter.
+type MoviesFilter struct {
+	Genre        string
+	Director     string
+	TitlePrefix  string
+	ReleasedFrom time.Time
+	ReleasedTo   time.Time
+}
+
+// Field to sort movies by.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Returns sort fields from a comma separated list like "genre,-release_date",
+// where a leading minus sorts descending.
+func ParseSort(s string) ([]SortField, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var sort []SortField
+
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ",") {
+		field := SortField{Field: strings.TrimSpace(part)}
+
+		if strings.HasPrefix(field.Field, "-") {
+			field.Field = field.Field[1:]
+			field.Desc = true
+		}
+
+		if _, ok := sortColumns[field.Field]; !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidSort, field.Field)
+		}
+
+		if seen[field.Field] {
+			return nil, fmt.Errorf("%w: field %q repeated", ErrInvalidSort, field.Field)
+		}
+
+		seen[field.Field] = true
+		sort = append(sort, field)
+	}
+
+	return sort, nil
+}
+
+// Returns canonical string form of sort fields.
+func FormatSort(sort []SortField) string {
+	parts := make([]string, len(sort))
+
+	for i, field := range sort {
+		parts[i] = field.Field
+
+		if field.Desc {
+			parts[i] = "-" + field.Field
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Returns value of a sort field of movie as stored in cursors.
+func sortValue(movie *models.Movie, field string) string {
+	switch field {
+	case "title":
+		return movie.Title
+	case "release_date":
+		return movie.ReleaseDate.Format(time.DateOnly)
+	case "genre":
+		return movie.Genre
+	case "director":
+		return movie.Director
+	}
+
+	return ""
+}
+
+// Collects the conditions and positional arguments of a statement.
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// Returns placeholder for a new argument.
+func (qb *queryBuilder) arg(value any) string {
+	qb.args = append(qb.args, value)
+
+	return fmt.Sprintf("$%d", len(qb.args))
+}
+
+// Returns WHERE clause joining all conditions, empty when there are none.
+func (qb *queryBuilder) where() string {
+	if len(qb.conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(qb.conditions, " AND ")
+}
+
+// Returns the statement selecting a page of movies and its arguments. Movies
+// are ordered by sort and then by id, so the order is total and the cursor
+// condition can pick up exactly after the last movie of the previous page.
+func buildMoviesPageQuery(query MoviesQuery, c *cursor) (string, []any) {
+	qb := &queryBuilder{}
+
+	filter := query.Filter
+
+	if filter.Genre != "" {
+		qb.conditions = append(qb.conditions, "genre = "+qb.arg(filter.Genre))
+	}
+
+	if filter.Director != "" {
+		qb.conditions = append(qb.conditions, "director = "+qb.arg(filter.Director))
+	}
+
+	if filter.TitlePrefix != "" {
+		qb.conditions = append(qb.conditions, "title ILIKE "+qb.arg(escapeLike(filter.TitlePrefix)+"%"))
+	}
+
+	if !filter.ReleasedFrom.IsZero() {
+		qb.conditions = append(qb.conditions, "release_date >= "+qb.arg(filter.ReleasedFrom.Format(time.DateOnly)))
+	}
+
+	if !filter.ReleasedTo.IsZero() {
+		qb.conditions = append(qb.conditions, "release_date <= "+qb.arg(filter.ReleasedTo.Format(time.DateOnly)))
+	}
+
+	if c != nil {
+		qb.conditions = append(qb.conditions, cursorCondition(qb, query.Sort, c))
+	}
+
+	order := make([]string, 0, len(query.Sort)+1)
+
+	for _, field := range query.Sort {
+		if field.Desc {
+			order = append(order, sortColumns[field.Field]+" DESC")
+		} else {
+			order = append(order, sortColumns[field.Field]+" ASC")
+		}
+	}
+
+	order = append(order, "movie_id ASC")
+
+	// Fetch one extra movie to know whether there is a next page.
+	statement := "SELECT " + movieColumns + " FROM movies" + qb.where() + " ORDER BY " + strings.Join(order, ", ") + " LIMIT " + qb.arg(query.Limit+1) + ";"
+
+	return statement, qb.args
+}
+
+// Returns condition matching movies that sort after the cursor. For sort
+// (a, -b) it is: a > $1 OR (a = $1 AND b < $2) OR (a = $1 AND b = $2 AND id > $3).
+func cursorCondition(qb *queryBuilder, sort []SortField, c *cursor) string {
+	placeholders := make([]string, len(sort))
+
+	for i, value := range c.Values {
+		placeholders[i] = qb.arg(value)
+	}
+
+	idPlaceholder := qb.arg(c.ID)
+
+	var alternatives []string
+
+	for i := 0; i <= len(sort); i++ {
+		var parts []string
+
+		for j := 0; j < i; j++ {
+			parts = append(parts, sortColumns[sort[j].Field]+" = "+placeholders[j])
+		}
+
+		if i < len(sort) {
+			op := " > "
+
+			if sort[i].Desc {
+				op = " < "
+			}
+
+			parts = append(parts, sortColumns[sort[i].Field]+op+placeholders[i])
+		} else {
+			parts = append(parts, "movie_id > "+idPlaceholder)
+		}
+
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// Escapes LIKE wildcards so user input only matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Returns a SQL expression escaping the HTML special characters of the text
+// in column. Postgres reads the entities as single tokens, so they are never
+// split by highlighting.
+func escapedHTML(column string) string {
+	return "replace(replace(replace(" + column + ", '&', '&amp;'), '<', '&lt;'), '>', '&gt;')"
+}
@@ -0,0 +1,158 @@
+package movies
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		sort    string
+		want    []SortField
+		wantErr error
+	}{
+		{
+			name: "empty",
+			sort: "",
+			want: nil,
+		},
+		{
+			name: "ascending and descending",
+			sort: "genre,-release_date",
+			want: []SortField{{Field: "genre"}, {Field: "release_date", Desc: true}},
+		},
+		{
+			name:    "unknown field",
+			sort:    "description",
+			wantErr: ErrInvalidSort,
+		},
+		{
+			name:    "sql in field",
+			sort:    "title; DROP TABLE movies",
+			wantErr: ErrInvalidSort,
+		},
+		{
+			name:    "repeated field",
+			sort:    "title,-title",
+			wantErr: ErrInvalidSort,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseSort(test.sort)
+
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBuildMoviesPageQuery(t *testing.T) {
+	t.Run("no filter", func(t *testing.T) {
+		statement, args := buildMoviesPageQuery(MoviesQuery{Limit: 20}, nil)
+
+		want := "SELECT movie_id, title, release_date, genre, director, description, version, created_at, updated_at FROM movies ORDER BY movie_id ASC LIMIT $1;"
+
+		if statement != want {
+			t.Errorf("got %s, want %s", statement, want)
+		}
+
+		if !reflect.DeepEqual(args, []any{21}) {
+			t.Errorf("got args %v, want %v", args, []any{21})
+		}
+	})
+
+	t.Run("filter and sort", func(t *testing.T) {
+		query := MoviesQuery{
+			Limit: 10,
+			Filter: MoviesFilter{
+				Genre:        "Drama",
+				Director:     "Frank Darabont",
+				TitlePrefix:  "100%_",
+				ReleasedFrom: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+				ReleasedTo:   time.Date(2000, time.December, 31, 0, 0, 0, 0, time.UTC),
+			},
+			Sort: []SortField{{Field: "release_date", Desc: true}},
+		}
+
+		statement, args := buildMoviesPageQuery(query, nil)
+
+		want := "SELECT movie_id, title, release_date, genre, director, description, version, created_at, updated_at FROM movies WHERE genre = $1 AND director = $2 AND title ILIKE $3 AND release_date >= $4 AND release_date <= $5 ORDER BY release_date DESC, movie_id ASC LIMIT $6;"
+
+		if statement != want {
+			t.Errorf("got %s, want %s", statement, want)
+		}
+
+		wantArgs := []any{"Drama", "Frank Darabont", `100\%\_%`, "1990-01-01", "2000-12-31", 11}
+
+		if !reflect.DeepEqual(args, wantArgs) {
+			t.Errorf("got args %v, want %v", args, wantArgs)
+		}
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+		query := MoviesQuery{
+			Limit: 10,
+			Sort:  []SortField{{Field: "genre"}, {Field: "release_date", Desc: true}},
+		}
+
+		c := &cursor{Sort: "genre,-release_date", Values: []string{"Drama", "1994-09-23"}, ID: id}
+
+		statement, args := buildMoviesPageQuery(query, c)
+
+		want := "SELECT movie_id, title, release_date, genre, director, description, version, created_at, updated_at FROM movies WHERE ((genre > $1) OR (genre = $1 AND release_date < $2) OR (genre = $1 AND release_date = $2 AND movie_id > $3)) ORDER BY genre ASC, release_date DESC, movie_id ASC LIMIT $4;"
+
+		if statement != want {
+			t.Errorf("got %s, want %s", statement, want)
+		}
+
+		wantArgs := []any{"Drama", "1994-09-23", id, 11}
+
+		if !reflect.DeepEqual(args, wantArgs) {
+			t.Errorf("got args %v, want %v", args, wantArgs)
+		}
+	})
+}
+
+func TestCursor(t *testing.T) {
+	sort := []SortField{{Field: "title", Desc: true}}
+
+	c := cursor{Sort: FormatSort(sort), Values: []string{"Heat"}, ID: uuid.New()}
+
+	t.Run("round trip", func(t *testing.T) {
+		got, err := decodeCursor(encodeCursor(c), sort)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, c) {
+			t.Errorf("got %v, want %v", got, c)
+		}
+	})
+
+	t.Run("different sort", func(t *testing.T) {
+		if _, err := decodeCursor(encodeCursor(c), nil); err != ErrInvalidCursor {
+			t.Errorf("got %v, want %v", err, ErrInvalidCursor)
+		}
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		if _, err := decodeCursor("not a cursor", sort); err != ErrInvalidCursor {
+			t.Errorf("got %v, want %v", err, ErrInvalidCursor)
+		}
+	})
+}
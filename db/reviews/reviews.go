@@ -0,0 +1,128 @@
+// This package provides methods to interact with the reviews database.
+package reviews
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"moviepin/models"
+)
+
+type ReviewsRepository interface {
+	GetReviews(ctx context.Context, movieID string) ([]*models.Review, error)
+	GetReview(ctx context.Context, movieID string, reviewID string) (*models.Review, error)
+	AddReview(ctx context.Context, review models.Review) error
+	UpdateReview(ctx context.Context, movieID string, reviewID string, review models.Review) error
+	DeleteReview(ctx context.Context, movieID string, reviewID string) error
+}
+
+var (
+	// Error returned when review does not exist.
+	ErrNotExists = errors.New("review does not exist")
+)
+
+type Reviews struct {
+	db *sql.DB
+}
+
+func NewReview(db *sql.DB) *Reviews {
+	return &Reviews{db: db}
+}
+
+// Returns slice of all reviews of a movie.
+func (rv Reviews) GetReviews(ctx context.Context, movieID string) ([]*models.Review, error) {
+	rows, err := rv.db.QueryContext(ctx, "SELECT review_id, user_id, movie_id, rating, review_text, created_at, updated_at FROM reviews WHERE movie_id = $1 ORDER BY created_at DESC;", movieID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	reviews := make([]*models.Review, 0)
+
+	for rows.Next() {
+		review := &models.Review{}
+
+		if err := rows.Scan(&review.ID, &review.UserID, &review.MovieID, &review.Rating, &review.ReviewText, &review.CreatedAt, &review.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+// Returns particular review of a movie.
+func (rv Reviews) GetReview(ctx context.Context, movieID string, reviewID string) (*models.Review, error) {
+	row := rv.db.QueryRowContext(ctx, "SELECT review_id, user_id, movie_id, rating, review_text, created_at, updated_at FROM reviews WHERE movie_id = $1 AND review_id = $2;", movieID, reviewID)
+
+	review := &models.Review{}
+
+	if err := row.Scan(&review.ID, &review.UserID, &review.MovieID, &review.Rating, &review.ReviewText, &review.CreatedAt, &review.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotExists
+		}
+
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// Adds review to the database.
+func (rv Reviews) AddReview(ctx context.Context, review models.Review) error {
+	if _, err := rv.db.ExecContext(ctx, "INSERT INTO reviews(review_id, user_id, movie_id, rating, review_text, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7);", review.ID, review.UserID, review.MovieID, review.Rating, review.ReviewText, review.CreatedAt, review.UpdatedAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Updates rating and text of a review in the database.
+func (rv Reviews) UpdateReview(ctx context.Context, movieID string, reviewID string, review models.Review) error {
+	result, err := rv.db.ExecContext(ctx, "UPDATE reviews SET rating=$1, review_text=$2, updated_at=$3 WHERE movie_id=$4 AND review_id=$5;", review.Rating, review.ReviewText, review.UpdatedAt, movieID, reviewID)
+
+	if err != nil {
+		return err
+	}
+
+	num, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if num == 0 {
+		return ErrNotExists
+	}
+
+	return nil
+}
+
+// Deletes a review from the database.
+func (rv Reviews) DeleteReview(ctx context.Context, movieID string, reviewID string) error {
+	result, err := rv.db.ExecContext(ctx, "DELETE FROM reviews WHERE movie_id=$1 AND review_id=$2;", movieID, reviewID)
+
+	if err != nil {
+		return err
+	}
+
+	num, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if num == 0 {
+		return ErrNotExists
+	}
+
+	return nil
+}
@@ -5,27 +5,51 @@ CREATE DATABASE moviepin;
 -- Enable the UUID extension
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
+-- Enable trigram matching for typo tolerant title lookups
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 CREATE TABLE Users (
     user_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    username TEXT NOT NULL,
-    email TEXT NOT NULL,
+    username TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL UNIQUE,
+    role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'editor', 'member')),
     password_hash TEXT NOT NULL
 );
 
+CREATE TABLE Sessions (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
 CREATE TABLE Movies (
     movie_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     title TEXT NOT NULL,
     release_date DATE,
     genre TEXT,
     director TEXT,
-    description TEXT
+    description TEXT,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(director, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'C')
+    ) STORED
 );
 
+CREATE INDEX movies_search_vector_idx ON Movies USING GIN (search_vector);
+
+CREATE INDEX movies_title_trgm_idx ON Movies USING GIN (title gin_trgm_ops);
+
 CREATE TABLE Reviews (
     review_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID REFERENCES Users(user_id),
     movie_id UUID REFERENCES Movies(movie_id),
-    rating INTEGER,
+    -- Whole stars from 1 to 5.
+    rating INTEGER CONSTRAINT reviews_rating_check CHECK (rating BETWEEN 1 AND 5),
     review_text TEXT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
@@ -33,16 +57,75 @@ CREATE TABLE Reviews (
 
 CREATE TABLE Lists (
     list_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    user_id UUID REFERENCES Users(user_id),
-    title TEXT,
-    description TEXT,
+    user_id UUID NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    description TEXT DEFAULT '',
+    visibility TEXT NOT NULL DEFAULT 'private' CHECK (visibility IN ('private', 'unlisted', 'public')),
+    -- Names the list in its shareable path, /l/{username}/{slug}.
+    slug TEXT NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT lists_user_id_slug_key UNIQUE (user_id, slug)
 );
 
+CREATE INDEX lists_user_id_idx ON Lists (user_id);
+
 CREATE TABLE ListItems (
     list_item_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    list_id UUID REFERENCES Lists(list_id),
-    movie_id UUID REFERENCES Movies(movie_id),
-    position INTEGER
+    list_id UUID NOT NULL REFERENCES Lists(list_id) ON DELETE CASCADE,
+    -- Deleting a movie would take its items along, which is why the API
+    -- refuses to delete a movie that is in a list.
+    movie_id UUID NOT NULL REFERENCES Movies(movie_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    UNIQUE (list_id, movie_id),
+    -- Deferred, so that reordering can swap positions within a transaction.
+    CONSTRAINT listitems_list_id_position_key UNIQUE (list_id, position) DEFERRABLE INITIALLY DEFERRED
 );
+
+-- Moves the items after a deleted one up, also when a deleted movie takes
+-- its items along. The list row is locked first, like every change to its
+-- items does.
+CREATE FUNCTION listitems_close_gap() RETURNS trigger AS $$
+BEGIN
+    PERFORM 1 FROM Lists WHERE list_id = OLD.list_id FOR UPDATE;
+
+    UPDATE ListItems SET position = position - 1 WHERE list_id = OLD.list_id AND position > OLD.position;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER listitems_close_gap AFTER DELETE ON ListItems
+    FOR EACH ROW EXECUTE FUNCTION listitems_close_gap();
+
+-- What a user is doing about a movie, kept apart from their reviews. Only
+-- watched movies have the date they were watched on.
+CREATE TABLE WatchStatuses (
+    user_id UUID NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+    -- The API refuses to delete a movie users have a status for.
+    movie_id UUID NOT NULL REFERENCES Movies(movie_id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('want_to_watch', 'watching', 'watched')),
+    watched_at DATE CHECK ((status = 'watched') = (watched_at IS NOT NULL)),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, movie_id)
+);
+
+-- Watchlists are read page by page, most recently changed first.
+CREATE INDEX watchstatuses_user_id_updated_at_idx ON WatchStatuses (user_id, updated_at DESC, movie_id DESC);
+
+-- Every viewing of a movie a user logs, possibly many per movie. An entry
+-- keeps its date and notes when the review it links to is deleted.
+CREATE TABLE DiaryEntries (
+    diary_entry_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+    -- The API refuses to delete a movie logged in a diary.
+    movie_id UUID NOT NULL REFERENCES Movies(movie_id) ON DELETE CASCADE,
+    review_id UUID REFERENCES Reviews(review_id) ON DELETE SET NULL,
+    watched_on DATE NOT NULL,
+    rewatch BOOLEAN NOT NULL DEFAULT FALSE,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Diaries are read a month or a year at a time.
+CREATE INDEX diaryentries_user_id_watched_on_idx ON DiaryEntries (user_id, watched_on);
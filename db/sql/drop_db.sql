@@ -1,11 +1,19 @@
+DROP TABLE IF EXISTS DiaryEntries;
+
+DROP TABLE IF EXISTS WatchStatuses;
+
 DROP TABLE IF EXISTS ListItems;
 
+DROP FUNCTION IF EXISTS listitems_close_gap();
+
 DROP TABLE IF EXISTS Lists;
 
 DROP TABLE IF EXISTS Reviews;
 
 DROP TABLE IF EXISTS Movies;
 
+DROP TABLE IF EXISTS Sessions;
+
 DROP TABLE IF EXISTS Users;
 
 DROP DATABASE IF EXISTS moviepin;
@@ -0,0 +1,101 @@
+// This package provides methods to interact with the users database.
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"moviepin/models"
+
+	"github.com/lib/pq"
+)
+
+type UsersRepository interface {
+	AddUser(ctx context.Context, user models.User) error
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	AddSession(ctx context.Context, session models.Session) error
+	GetSessionUser(ctx context.Context, tokenHash string) (*models.User, error)
+}
+
+var (
+	// Error returned when user does not exist.
+	ErrNotExists = errors.New("user does not exist")
+
+	// Error returned when username or email is already taken.
+	ErrAlreadyExists = errors.New("user already exists")
+
+	// Error returned when session does not exist or has expired.
+	ErrSessionNotExists = errors.New("session does not exist")
+)
+
+// Postgres error code for unique constraint violations.
+const uniqueViolation = "23505"
+
+type Users struct {
+	db *sql.DB
+}
+
+func NewUser(db *sql.DB) *Users {
+	return &Users{db: db}
+}
+
+// Adds user to the database.
+func (u Users) AddUser(ctx context.Context, user models.User) error {
+	_, err := u.db.ExecContext(ctx, "INSERT INTO users(user_id, username, email, role, password_hash) VALUES($1, $2, $3, $4, $5);", user.ID, user.Username, user.Email, user.Role, user.PasswordHash)
+
+	if err != nil {
+		var pqErr *pq.Error
+
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrAlreadyExists
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Returns user with the given username.
+func (u Users) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	row := u.db.QueryRowContext(ctx, "SELECT user_id, username, email, role, password_hash FROM users WHERE username = $1;", username)
+
+	user := &models.User{}
+
+	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.PasswordHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotExists
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Adds session to the database.
+func (u Users) AddSession(ctx context.Context, session models.Session) error {
+	if _, err := u.db.ExecContext(ctx, "INSERT INTO sessions(token_hash, user_id, created_at, expires_at) VALUES($1, $2, $3, $4);", session.TokenHash, session.UserID, session.CreatedAt, session.ExpiresAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Returns user owning an unexpired session.
+func (u Users) GetSessionUser(ctx context.Context, tokenHash string) (*models.User, error) {
+	row := u.db.QueryRowContext(ctx, "SELECT u.user_id, u.username, u.email, u.role, u.password_hash FROM sessions s JOIN users u ON s.user_id = u.user_id WHERE s.token_hash = $1 AND s.expires_at > CURRENT_TIMESTAMP;", tokenHash)
+
+	user := &models.User{}
+
+	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.PasswordHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotExists
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
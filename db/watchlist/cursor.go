@@ -0,0 +1,49 @@
+package watchlist
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// Error returned when a page cursor cannot be decoded.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Position of the last status of a page. Clients only ever see it encoded,
+// so its contents can change without breaking them.
+type cursor struct {
+	// Update time of the last status.
+	UpdatedAt time.Time `json:"u"`
+
+	// Movie of the last status, which breaks ties between equal times.
+	MovieID uuid.UUID `json:"m"`
+}
+
+// Returns opaque string form of a cursor.
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Returns cursor from its opaque string form.
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err = json.Unmarshal(b, &c); err != nil || c.MovieID == uuid.Nil || c.UpdatedAt.IsZero() {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
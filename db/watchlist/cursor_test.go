@@ -0,0 +1,28 @@
+package watchlist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursor(t *testing.T) {
+	c := cursor{UpdatedAt: time.Date(2024, time.March, 2, 21, 40, 0, 123456000, time.UTC), MovieID: uuid.New()}
+
+	decoded, err := decodeCursor(encodeCursor(c))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !decoded.UpdatedAt.Equal(c.UpdatedAt) || decoded.MovieID != c.MovieID {
+		t.Errorf("got %+v, want %+v", decoded, c)
+	}
+
+	for _, s := range []string{"x", "e30", encodeCursor(cursor{MovieID: uuid.New()})} {
+		if _, err := decodeCursor(s); err != ErrInvalidCursor {
+			t.Errorf("decoding %q got error %v, want %v", s, err, ErrInvalidCursor)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// This package provides methods to interact with the watch statuses database.
+package watchlist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"moviepin/models"
+
+	"github.com/lib/pq"
+)
+
+type WatchlistRepository interface {
+	GetStatus(ctx context.Context, userID string, movieID string) (*models.WatchStatus, error)
+	SetStatus(ctx context.Context, status models.WatchStatus) error
+	DeleteStatus(ctx context.Context, userID string, movieID string) error
+	GetWatchlistPage(ctx context.Context, userID string, query WatchlistQuery) (*models.WatchlistPage, error)
+}
+
+var (
+	// Error returned when user gave the movie no status.
+	ErrNotExists = errors.New("watch status does not exist")
+
+	// Error returned when the movie of a status does not exist.
+	ErrMovieNotExists = errors.New("movie does not exist")
+)
+
+// Postgres error code for foreign key violations.
+const foreignKeyViolation = "23503"
+
+// Constraint tying a status to its movie.
+const movieForeignKey = "watchstatuses_movie_id_fkey"
+
+// Describes which page of a watchlist to return.
+type WatchlistQuery struct {
+	// Maximum number of statuses in the page.
+	Limit int
+
+	// Cursor returned along with the previous page, empty for the first page.
+	Cursor string
+
+	// Only statuses of this kind are returned, all of them when empty.
+	Status string
+}
+
+type Watchlist struct {
+	db *sql.DB
+}
+
+func NewWatchlist(db *sql.DB) *Watchlist {
+	return &Watchlist{db: db}
+}
+
+// Returns the status a user gave a movie.
+func (wl Watchlist) GetStatus(ctx context.Context, userID string, movieID string) (*models.WatchStatus, error) {
+	row := wl.db.QueryRowContext(ctx, "SELECT user_id, movie_id, status, watched_at, updated_at FROM watchstatuses WHERE user_id = $1 AND movie_id = $2;", userID, movieID)
+
+	status := &models.WatchStatus{}
+
+	if err := row.Scan(&status.UserID, &status.MovieID, &status.Status, &status.WatchedAt, &status.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotExists
+		}
+
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// Sets the status a user gives a movie, replacing the one they gave before.
+func (wl Watchlist) SetStatus(ctx context.Context, status models.WatchStatus) error {
+	_, err := wl.db.ExecContext(ctx, `INSERT INTO watchstatuses(user_id, movie_id, status, watched_at, updated_at) VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, movie_id) DO UPDATE SET status = EXCLUDED.status, watched_at = EXCLUDED.watched_at, updated_at = EXCLUDED.updated_at;`,
+		status.UserID, status.MovieID, status.Status, status.WatchedAt, status.UpdatedAt)
+
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation && pqErr.Constraint == movieForeignKey {
+		return ErrMovieNotExists
+	}
+
+	return err
+}
+
+// Removes the status a user gave a movie.
+func (wl Watchlist) DeleteStatus(ctx context.Context, userID string, movieID string) error {
+	result, err := wl.db.ExecContext(ctx, "DELETE FROM watchstatuses WHERE user_id = $1 AND movie_id = $2;", userID, movieID)
+
+	if err != nil {
+		return err
+	}
+
+	num, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if num == 0 {
+		return ErrNotExists
+	}
+
+	return nil
+}
+
+// Returns a page of the statuses of a user with their movies, most recently
+// changed first. Pages are read with keyset pagination, so they stay stable
+// while statuses change.
+func (wl Watchlist) GetWatchlistPage(ctx context.Context, userID string, query WatchlistQuery) (*models.WatchlistPage, error) {
+	conditions := "ws.user_id = $1"
+	args := []any{userID}
+
+	if query.Status != "" {
+		args = append(args, query.Status)
+		conditions += " AND ws.status = $" + strconv.Itoa(len(args))
+	}
+
+	if query.Cursor != "" {
+		c, err := decodeCursor(query.Cursor)
+
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, c.UpdatedAt, c.MovieID)
+		conditions += " AND (ws.updated_at, ws.movie_id) < ($" + strconv.Itoa(len(args)-1) + ", $" + strconv.Itoa(len(args)) + ")"
+	}
+
+	// Fetch one extra status to know whether there is a next page.
+	args = append(args, query.Limit+1)
+
+	rows, err := wl.db.QueryContext(ctx, `SELECT ws.user_id, ws.status, ws.watched_at, ws.updated_at,
+		m.movie_id, m.title, m.release_date, m.genre, m.director, m.description, m.version, m.created_at, m.updated_at
+		FROM watchstatuses ws JOIN movies m ON m.movie_id = ws.movie_id
+		WHERE `+conditions+` ORDER BY ws.updated_at DESC, ws.movie_id DESC LIMIT $`+strconv.Itoa(len(args))+";", args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	statuses := make([]*models.WatchStatus, 0)
+
+	for rows.Next() {
+		status := &models.WatchStatus{Movie: &models.Movie{}}
+		movie := status.Movie
+
+		if err := rows.Scan(&status.UserID, &status.Status, &status.WatchedAt, &status.UpdatedAt, &movie.ID, &movie.Title, &movie.ReleaseDate, &movie.Genre, &movie.Director, &movie.Description, &movie.Version, &movie.CreatedAt, &movie.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		status.MovieID = movie.ID
+
+		statuses = append(statuses, status)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &models.WatchlistPage{Items: statuses}
+
+	if len(statuses) > query.Limit {
+		page.Items = statuses[:query.Limit]
+		last := page.Items[query.Limit-1]
+
+		page.NextCursor = encodeCursor(cursor{UpdatedAt: last.UpdatedAt, MovieID: last.MovieID})
+	}
+
+	return page, nil
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"moviepin/auth"
+	"moviepin/db/users"
+	"moviepin/models"
+	"moviepin/problem"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// ErrFailedToLogin is returned when failed to log in.
+	ErrFailedToLogin = "failed to log in"
+
+	// ErrInvalidCredentials is returned when username or password is wrong.
+	ErrInvalidCredentials = "invalid username or password"
+
+	// ErrUnauthorized is returned when request needs an authenticated user.
+	ErrUnauthorized = "authentication required"
+)
+
+// Hash compared against when the user does not exist, so that unknown
+// usernames take as long to reject as wrong passwords.
+const dummyPasswordHash = "$2a$10$a6DWLlrz/A/gFikdK8kjzuVA4jVCYgPSkkfBDsw6r3snA.tghGX5K"
+
+type AuthHandler struct {
+	db       users.UsersRepository
+	logger   *slog.Logger
+	validate *validator.Validate
+}
+
+// Returns a new AuthHandler.
+func NewAuthHandler(db users.UsersRepository, logger *slog.Logger, validate *validator.Validate) *AuthHandler {
+	return &AuthHandler{db: db, logger: logger, validate: validate}
+}
+
+// Checks credentials sent in request and responds with a new token.
+func (ah AuthHandler) postLogin(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		ah.logger.ErrorContext(r.Context(), ErrFailedToLogin, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToLogin)
+		return
+	}
+
+	var credentials models.Credentials
+
+	if err = json.Unmarshal(body, &credentials); err != nil {
+		ah.logger.InfoContext(r.Context(), ErrFailedToLogin, "error", err)
+		problem.WriteInvalid(w, ErrFailedToLogin, err)
+		return
+	}
+
+	if err = ah.validate.Struct(credentials); err != nil {
+		ah.logger.InfoContext(r.Context(), ErrFailedToLogin, "error", err)
+		problem.WriteInvalid(w, ErrFailedToLogin, err)
+		return
+	}
+
+	user, err := ah.db.GetUserByUsername(r.Context(), credentials.Username)
+
+	if err != nil && err != users.ErrNotExists {
+		ah.logger.ErrorContext(r.Context(), ErrFailedToLogin, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToLogin)
+		return
+	}
+
+	if err == users.ErrNotExists {
+		bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(credentials.Password))
+		problem.Write(w, http.StatusUnauthorized, ErrInvalidCredentials)
+		return
+	}
+
+	if err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(credentials.Password)); err != nil {
+		problem.Write(w, http.StatusUnauthorized, ErrInvalidCredentials)
+		return
+	}
+
+	token, tokenHash, err := auth.NewToken()
+
+	if err != nil {
+		ah.logger.ErrorContext(r.Context(), ErrFailedToLogin, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToLogin)
+		return
+	}
+
+	now := time.Now().UTC()
+
+	session := models.Session{
+		TokenHash: tokenHash,
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(auth.SessionTTL),
+	}
+
+	if err = ah.db.AddSession(r.Context(), session); err != nil {
+		ah.logger.ErrorContext(r.Context(), ErrFailedToLogin, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToLogin)
+		return
+	}
+
+	tokenJson, err := json.Marshal(models.Token{Token: token, ExpiresAt: session.ExpiresAt})
+
+	if err != nil {
+		ah.logger.ErrorContext(r.Context(), ErrFailedToLogin, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToLogin)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(tokenJson)
+}
+
+// Methods the login route allows, advertised by Options and 405 responses.
+const authMethods = "POST, OPTIONS"
+
+// Responds with allowed methods.
+func (ah AuthHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Methods", authMethods)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Returns the authenticated user of a request. Responds with 401 and returns
+// false when the request is anonymous.
+func requireUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		problem.Write(w, http.StatusUnauthorized, ErrUnauthorized)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// Returns the authenticated user of a request when their role is granted perm.
+// Responds with 401 or 403 and returns false otherwise.
+func authorize(w http.ResponseWriter, r *http.Request, policy *auth.Policy, perm auth.Permission) (*models.User, bool) {
+	user, ok := requireUser(w, r)
+
+	if !ok {
+		return nil, false
+	}
+
+	if !policy.Allows(user, perm) {
+		problem.Write(w, http.StatusForbidden, fmt.Sprintf("forbidden: role %q is not allowed to %s", user.Role, perm))
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (ah AuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		ah.postLogin(w, r)
+	case http.MethodOptions:
+		ah.Options(w, r)
+	default:
+		writeMethodNotAllowed(w, r, authMethods)
+	}
+}
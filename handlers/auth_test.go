@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"moviepin/auth"
+	"moviepin/db/users"
+	"moviepin/mocks"
+	"moviepin/models"
+)
+
+func TestPostLogin(t *testing.T) {
+	credentials := models.Credentials{
+		Username: mocks.User.Username,
+		Password: mocks.Password,
+	}
+
+	t.Run("login", func(t *testing.T) {
+		handler := NewAuthHandler(mocks.NewUsersRepository(), testLogger, testValidate)
+
+		req, err := http.NewRequest("POST", "/auth/login", jsonRequestBody(t, credentials))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postLogin(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+
+		var token models.Token
+
+		if err := json.Unmarshal(rr.Body.Bytes(), &token); err != nil {
+			t.Fatal(err)
+		}
+
+		if token.Token == "" {
+			t.Errorf("missing token")
+		}
+	})
+
+	t.Run("login wrong password", func(t *testing.T) {
+		handler := NewAuthHandler(mocks.NewUsersRepository(), testLogger, testValidate)
+
+		wrong := credentials
+		wrong.Password = "wrong password"
+
+		req, err := http.NewRequest("POST", "/auth/login", jsonRequestBody(t, wrong))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postLogin(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("login unknown user", func(t *testing.T) {
+		repo := mocks.NewUsersRepository()
+		repo.GetUserByUsernameError = users.ErrNotExists
+
+		handler := NewAuthHandler(repo, testLogger, testValidate)
+
+		req, err := http.NewRequest("POST", "/auth/login", jsonRequestBody(t, credentials))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postLogin(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("login session error", func(t *testing.T) {
+		repo := mocks.NewUsersRepository()
+		repo.AddSessionError = errors.New("error")
+
+		handler := NewAuthHandler(repo, testLogger, testValidate)
+
+		req, err := http.NewRequest("POST", "/auth/login", jsonRequestBody(t, credentials))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postLogin(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusInternalServerError)
+	})
+}
+
+func withUser(req *http.Request, user *models.User) *http.Request {
+	return req.WithContext(auth.NewContext(req.Context(), user))
+}
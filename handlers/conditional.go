@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviepin/db/movies"
+	"moviepin/problem"
+)
+
+// ErrPreconditionFailed is returned when If-Match does not match the movie.
+const ErrPreconditionFailed = "movie was changed since it was read, get it again and retry"
+
+// Returns the strong ETag of a movie at version.
+func movieETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Returns a strong ETag derived from the bytes of a representation, for
+// resources that have no version of their own.
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Reports whether an If-Match header value matches etag. Comparison is
+// strong, so weak tags never match.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks the If-Match header of a write to the movie with id. Returns the
+// version to compare-and-swap against, zero when the request has no
+// If-Match, and false after responding when the precondition fails.
+func (mh MoviesHandler) checkIfMatch(w http.ResponseWriter, r *http.Request, id string, detail string) (int, bool) {
+	header := r.Header.Get("If-Match")
+
+	if header == "" {
+		return 0, true
+	}
+
+	movie, err := mh.db.GetMovie(r.Context(), id)
+
+	// A missing movie has no current representation to match.
+	if err == movies.ErrNotExists {
+		problem.Write(w, http.StatusPreconditionFailed, ErrPreconditionFailed)
+		return 0, false
+	}
+
+	if err != nil {
+		mh.logger.ErrorContext(r.Context(), detail, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), detail)
+		return 0, false
+	}
+
+	if !etagMatches(header, movieETag(movie.Version)) {
+		problem.Write(w, http.StatusPreconditionFailed, ErrPreconditionFailed)
+		return 0, false
+	}
+
+	return movie.Version, true
+}
+
+// Sets the validators of a GET response and reports whether the copy the
+// client already has is current, in which case it responds with 304.
+// If-None-Match wins over If-Modified-Since, as RFC 9110 asks.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		if !etagMatchesWeak(header, etag) {
+			return false
+		}
+
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+
+	// Last-Modified only has second precision.
+	if err != nil || lastModified.IsZero() || lastModified.Truncate(time.Second).After(since) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// Reports whether an If-None-Match header value matches etag. Comparison is
+// weak, so W/ prefixes are ignored.
+func etagMatchesWeak(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
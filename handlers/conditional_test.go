@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"moviepin/auth"
+	"moviepin/db/movies"
+	"moviepin/mocks"
+	"moviepin/patch"
+)
+
+const moviePath = "/movies/6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"1"`, true},
+		{`"2"`, false},
+		{`"2", "1"`, true},
+		{`*`, true},
+		{`W/"1"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, movieETag(1)); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestGetMovieETag(t *testing.T) {
+	handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+	req, err := http.NewRequest("GET", moviePath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	handler.getMovie(rr, req)
+
+	if got, want := rr.Header().Get("ETag"), movieETag(mocks.MovieVersion); got != want {
+		t.Errorf("wrong ETag, got %v want %v", got, want)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	current := movieETag(mocks.MovieVersion)
+
+	tests := []struct {
+		name      string
+		method    string
+		ifMatch   string
+		updateErr error
+		getErr    error
+		want      int
+	}{
+		{"put matching", "PUT", current, nil, nil, http.StatusNoContent},
+		{"put stale", "PUT", `"7"`, nil, nil, http.StatusPreconditionFailed},
+		{"put changed meanwhile", "PUT", current, movies.ErrVersionMismatch, nil, http.StatusPreconditionFailed},
+		{"put missing movie", "PUT", current, nil, movies.ErrNotExists, http.StatusPreconditionFailed},
+		{"put unconditional", "PUT", "", nil, nil, http.StatusNoContent},
+		{"patch matching", "PATCH", current, nil, nil, http.StatusNoContent},
+		{"patch stale", "PATCH", `"7"`, nil, nil, http.StatusPreconditionFailed},
+		{"patch changed meanwhile", "PATCH", "", movies.ErrVersionMismatch, nil, http.StatusPreconditionFailed},
+		{"delete matching", "DELETE", current, nil, nil, http.StatusNoContent},
+		{"delete stale", "DELETE", `"7"`, nil, nil, http.StatusPreconditionFailed},
+		{"delete changed meanwhile", "DELETE", current, movies.ErrVersionMismatch, nil, http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewMoviesRepository()
+			repo.GetMovieError = tt.getErr
+			repo.UpdateMovieError = tt.updateErr
+			repo.DeleteMovieError = tt.updateErr
+
+			handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest(tt.method, moviePath, movieRequestBody(t, &mocks.Movie))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, withUser(req, &mocks.Admin))
+
+			assertStatusCode(t, rr.Code, tt.want)
+
+			if tt.want == http.StatusNoContent && tt.method != "DELETE" {
+				if got, want := rr.Header().Get("ETag"), movieETag(mocks.MovieVersion+1); got != want {
+					t.Errorf("wrong ETag, got %v want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	lastModified := mocks.Movie.UpdatedAt.Format(http.TimeFormat)
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"matching etag", "If-None-Match", movieETag(mocks.MovieVersion), http.StatusNotModified},
+		{"weak matching etag", "If-None-Match", "W/" + movieETag(mocks.MovieVersion), http.StatusNotModified},
+		{"stale etag", "If-None-Match", `"7"`, http.StatusOK},
+		{"not modified since", "If-Modified-Since", lastModified, http.StatusNotModified},
+		{"modified since", "If-Modified-Since", mocks.Movie.UpdatedAt.Add(-time.Hour).Format(http.TimeFormat), http.StatusOK},
+		{"unconditional", "", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest("GET", moviePath, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.getMovie(rr, req)
+
+			assertStatusCode(t, rr.Code, tt.want)
+
+			if got := rr.Header().Get("Last-Modified"); got != lastModified {
+				t.Errorf("wrong Last-Modified, got %v want %v", got, lastModified)
+			}
+
+			if tt.want == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("304 response has a body")
+			}
+		})
+	}
+}
+
+func TestConditionalGetMovies(t *testing.T) {
+	handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+	req, err := http.NewRequest("GET", "/movies", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	handler.getMovies(rr, req)
+
+	etag := rr.Header().Get("ETag")
+
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+
+	req.Header.Set("If-None-Match", etag)
+
+	rr = httptest.NewRecorder()
+
+	handler.getMovies(rr, req)
+
+	assertStatusCode(t, rr.Code, http.StatusNotModified)
+}
+
+func TestAuthenticatedETagMatchesForWrites(t *testing.T) {
+	handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+	req, err := http.NewRequest("GET", moviePath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, withUser(req, &mocks.Editor))
+
+	assertStatusCode(t, rr.Code, http.StatusOK)
+
+	etag := rr.Header().Get("ETag")
+
+	if etag != movieETag(mocks.MovieVersion) {
+		t.Fatalf("wrong ETag, got %v want %v", etag, movieETag(mocks.MovieVersion))
+	}
+
+	// The status is not covered by the ETag, so it must not be revalidated.
+	req.Header.Set("If-None-Match", etag)
+
+	rr = httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, withUser(req, &mocks.Editor))
+
+	assertStatusCode(t, rr.Code, http.StatusOK)
+
+	req, err = http.NewRequest("PATCH", moviePath, strings.NewReader(`{"title":"Enemy"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", patch.MergeContentType)
+	req.Header.Set("If-Match", etag)
+
+	rr = httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, withUser(req, &mocks.Editor))
+
+	assertStatusCode(t, rr.Code, http.StatusNoContent)
+}
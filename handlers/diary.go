@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"moviepin/auth"
+	"moviepin/db/diary"
+	"moviepin/db/reviews"
+	"moviepin/models"
+	"moviepin/problem"
+	"moviepin/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+const (
+	// ErrDiaryEntryNotExists is returned when diary entry does not exist.
+	ErrDiaryEntryNotExists = "diary entry does not exist"
+
+	// ErrFailedToGetDiary is returned when failed to get diary entries.
+	ErrFailedToGetDiary = "failed to get diary"
+
+	// ErrFailedToGetDiaryEntry is returned when failed to get diary entry.
+	ErrFailedToGetDiaryEntry = "failed to get diary entry"
+
+	// ErrFailedToAddDiaryEntry is returned when failed to add diary entry.
+	ErrFailedToAddDiaryEntry = "failed to add diary entry"
+
+	// ErrFailedToDeleteDiaryEntry is returned when failed to delete diary entry.
+	ErrFailedToDeleteDiaryEntry = "failed to delete diary entry"
+
+	// ErrFailedToGetDiaryStats is returned when failed to get diary stats.
+	ErrFailedToGetDiaryStats = "failed to get diary stats"
+
+	// ErrNotDiaryOwner is returned when user reads or writes a diary of someone else.
+	ErrNotDiaryOwner = "forbidden: only the owner can use a diary"
+
+	// ErrNotOwnReview is returned when an entry links a review of someone else.
+	ErrNotOwnReview = "forbidden: only own reviews can be linked"
+)
+
+const (
+	// Number of genres and directors in stats when request does not set a limit.
+	defaultTopLimit = 10
+
+	// Largest number of genres and directors in stats a request can ask for.
+	maxTopLimit = 50
+)
+
+var (
+	errInvalidYear  = errors.New("year must be a number between 1 and 9999")
+	errInvalidMonth = errors.New("month must be a number between 1 and 12")
+	errInvalidTop   = fmt.Errorf("limit must be a number between 1 and %d", maxTopLimit)
+)
+
+type DiaryHandler struct {
+	diary    diary.DiaryRepository
+	reviews  reviews.ReviewsRepository
+	policy   *auth.Policy
+	logger   *slog.Logger
+	validate *validator.Validate
+}
+
+// Returns a new DiaryHandler.
+func NewDiaryHandler(diary diary.DiaryRepository, reviews reviews.ReviewsRepository, policy *auth.Policy, logger *slog.Logger, validate *validator.Validate) *DiaryHandler {
+	return &DiaryHandler{diary: diary, reviews: reviews, policy: policy, logger: logger, validate: validate}
+}
+
+// Returns the first day of the period asked for with the year and month
+// query parameters and the first day after it. Without a month the period
+// is the whole year.
+func diaryPeriod(r *http.Request) (time.Time, time.Time, error) {
+	values := r.URL.Query()
+
+	year, err := strconv.Atoi(values.Get("year"))
+
+	if err != nil || year < 1 || year > 9999 {
+		return time.Time{}, time.Time{}, errors.Join(errInvalidYear, err)
+	}
+
+	if values.Get("month") == "" {
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		return from, from.AddDate(1, 0, 0), nil
+	}
+
+	month, err := strconv.Atoi(values.Get("month"))
+
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, time.Time{}, errors.Join(errInvalidMonth, err)
+	}
+
+	from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+
+	return from, from.AddDate(0, 1, 0), nil
+}
+
+// Returns the year stats are restricted to with the year query parameter, 0
+// for all years, and the size of their top lists.
+func statsQuery(r *http.Request) (int, int, error) {
+	values := r.URL.Query()
+
+	year, limit := 0, defaultTopLimit
+
+	if value := values.Get("year"); value != "" {
+		var err error
+
+		if year, err = strconv.Atoi(value); err != nil || year < 1 || year > 9999 {
+			return 0, 0, errors.Join(errInvalidYear, err)
+		}
+	}
+
+	if value := values.Get("limit"); value != "" {
+		var err error
+
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxTopLimit {
+			return 0, 0, errors.Join(errInvalidTop, err)
+		}
+	}
+
+	return year, limit, nil
+}
+
+// Responds with the entries of the diary of the caller in a month or a year,
+// oldest first, with their movies.
+func (dh DiaryHandler) getEntries(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := utils.GetDiaryIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrDiaryEntryNotExists)
+		return
+	}
+
+	if !dh.ownDiary(w, r, userID, ErrFailedToGetDiary) {
+		return
+	}
+
+	from, to, err := diaryPeriod(r)
+
+	if err != nil {
+		dh.logger.InfoContext(r.Context(), ErrFailedToGetDiary, "error", err)
+		problem.WriteInvalid(w, ErrFailedToGetDiary, err)
+		return
+	}
+
+	entries, err := dh.diary.GetEntries(r.Context(), userID, from, to)
+
+	if err != nil {
+		dh.logger.ErrorContext(r.Context(), ErrFailedToGetDiary, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetDiary)
+		return
+	}
+
+	entriesJson, err := json.Marshal(entries)
+
+	if err != nil {
+		dh.logger.ErrorContext(r.Context(), ErrFailedToGetDiary, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetDiary)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(entriesJson)
+}
+
+// Responds with particular entry of the diary of the caller.
+func (dh DiaryHandler) getEntry(w http.ResponseWriter, r *http.Request) {
+	userID, entryID, err := utils.GetDiaryIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrDiaryEntryNotExists)
+		return
+	}
+
+	if !dh.ownDiary(w, r, userID, ErrFailedToGetDiaryEntry) {
+		return
+	}
+
+	if err = dh.validate.Var(entryID, "required,uuid"); err != nil {
+		dh.logger.InfoContext(r.Context(), ErrFailedToGetDiaryEntry, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToGetDiaryEntry, problem.FieldErrors(err, "entry_id"))
+		return
+	}
+
+	entry, err := dh.diary.GetEntry(r.Context(), userID, entryID)
+
+	if err == diary.ErrNotExists {
+		problem.Write(w, http.StatusNotFound, ErrDiaryEntryNotExists)
+		return
+	}
+
+	if err != nil {
+		dh.logger.ErrorContext(r.Context(), ErrFailedToGetDiaryEntry, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetDiaryEntry)
+		return
+	}
+
+	entryJson, err := json.Marshal(entry)
+
+	if err != nil {
+		dh.logger.ErrorContext(r.Context(), ErrFailedToGetDiaryEntry, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetDiaryEntry)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(entryJson)
+}
+
+// Logs a viewing sent in request in the diary of the caller. A linked review
+// must be one the caller wrote of the same movie.
+func (dh DiaryHandler) postEntry(w http.ResponseWriter, r *http.Request) {
+	user, ok := authorize(w, r, dh.policy, auth.PermWriteDiary)
+
+	if !ok {
+		return
+	}
+
+	userID, _, err := utils.GetDiaryIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrDiaryEntryNotExists)
+		return
+	}
+
+	if !dh.ownDiary(w, r, userID, ErrFailedToAddDiaryEntry) {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		dh.logger.ErrorContext(r.Context(), ErrFailedToAddDiaryEntry, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddDiaryEntry)
+		return
+	}
+
+	var entry models.DiaryEntry
+
+	if err = json.Unmarshal(body, &entry); err != nil {
+		dh.logger.InfoContext(r.Context(), ErrFailedToAddDiaryEntry, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddDiaryEntry, err)
+		return
+	}
+
+	if err = dh.validate.Struct(entry); err != nil {
+		dh.logger.InfoContext(r.Context(), ErrFailedToAddDiaryEntry, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddDiaryEntry, err)
+		return
+	}
+
+	// Fields managed by the server are never taken from the request.
+	entry.ID = uuid.New()
+	entry.UserID = user.ID
+	entry.CreatedAt = time.Now().UTC()
+	entry.Movie = nil
+
+	// Only the day is kept, as the client wrote it.
+	entry.WatchedOn = time.Date(entry.WatchedOn.Year(), entry.WatchedOn.Month(), entry.WatchedOn.Day(), 0, 0, 0, 0, time.UTC)
+
+	if entry.ReviewID != nil {
+		review, err := dh.reviews.GetReview(r.Context(), entry.MovieID.String(), entry.ReviewID.String())
+
+		if err == reviews.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrReviewNotExists)
+			return
+		}
+
+		if err != nil {
+			dh.logger.ErrorContext(r.Context(), ErrFailedToAddDiaryEntry, "error", err)
+			problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddDiaryEntry)
+			return
+		}
+
+		if review.UserID != user.ID {
+			problem.Write(w, http.StatusForbidden, ErrNotOwnReview)
+			return
+		}
+	}
+
+	if err = dh.diary.AddEntry(r.Context(), entry); err != nil {
+		if err == diary.ErrMovieNotExists {
+			problem.Write(w, http.StatusNotFound, ErrNotExists)
+			return
+		}
+
+		dh.logger.ErrorContext(r.Context(), ErrFailedToAddDiaryEntry, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddDiaryEntry)
+		return
+	}
+
+	entryJson, err := json.Marshal(entry)
+
+	if err != nil {
+		dh.logger.ErrorContext(r.Context(), ErrFailedToAddDiaryEntry, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddDiaryEntry)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/users/"+userID+"/diary/"+entry.ID.String())
+	w.WriteHeader(http.StatusCreated)
+	w.Write(entryJson)
+}
+
+// Deletes particular entry of the diary of the caller.
+func (dh DiaryHandler) deleteEntry(w http.ResponseWriter, r *http.Request) {
+	if _, ok := authorize(w, r, dh.policy, auth.PermWriteDiary); !ok {
+		return
+	}
+
+	userID, entryID, err := utils.GetDiaryIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrDiaryEntryNotExists)
+		return
+	}
+
+	if !dh.ownDiary(w, r, userID, ErrFailedToDeleteDiaryEntry) {
+		return
+	}
+
+	if err = dh.validate.Var(entryID, "required,uuid"); err != nil {
+		dh.logger.InfoContext(r.Context(), ErrFailedToDeleteDiaryEntry, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToDeleteDiaryEntry, problem.FieldErrors(err, "entry_id"))
+		return
+	}
+
+	if err = dh.diary.DeleteEntry(r.Context(), userID, entryID); err != nil {
+		if err == diary.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrDiaryEntryNotExists)
+			return
+		}
+
+		dh.logger.ErrorContext(r.Context(), ErrFailedToDeleteDiaryEntry, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToDeleteDiaryEntry)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Responds with the stats of the diary of the caller, over all years or the
+// one in the year query parameter.
+func (dh DiaryHandler) getStats(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromDiaryStatsPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrDiaryEntryNotExists)
+		return
+	}
+
+	if !dh.ownDiary(w, r, userID, ErrFailedToGetDiaryStats) {
+		return
+	}
+
+	year, limit, err := statsQuery(r)
+
+	if err != nil {
+		dh.logger.InfoContext(r.Context(), ErrFailedToGetDiaryStats, "error", err)
+		problem.WriteInvalid(w, ErrFailedToGetDiaryStats, err)
+		return
+	}
+
+	stats, err := dh.diary.GetStats(r.Context(), userID, year, limit)
+
+	if err != nil {
+		dh.logger.ErrorContext(r.Context(), ErrFailedToGetDiaryStats, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetDiaryStats)
+		return
+	}
+
+	statsJson, err := json.Marshal(stats)
+
+	if err != nil {
+		dh.logger.ErrorContext(r.Context(), ErrFailedToGetDiaryStats, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetDiaryStats)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(statsJson)
+}
+
+// Reports whether the diary of the user in path is the one of the caller,
+// writing a problem with detail when it is not.
+func (dh DiaryHandler) ownDiary(w http.ResponseWriter, r *http.Request, userID string, detail string) bool {
+	user, ok := requireUser(w, r)
+
+	if !ok {
+		return false
+	}
+
+	if err := dh.validate.Var(userID, "required,uuid"); err != nil {
+		dh.logger.InfoContext(r.Context(), detail, "error", err)
+		problem.WriteFieldErrors(w, detail, problem.FieldErrors(err, "user_id"))
+		return false
+	}
+
+	if userID != user.ID.String() {
+		problem.Write(w, http.StatusForbidden, ErrNotDiaryOwner)
+		return false
+	}
+
+	return true
+}
+
+// Methods the diary routes allow, advertised by Options and 405 responses.
+const diaryMethods = "GET, POST, DELETE, OPTIONS"
+
+// Responds with allowed methods.
+func (dh DiaryHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Methods", diaryMethods)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (dh DiaryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, err := utils.GetUserIDFromDiaryStatsPath(r.URL.Path); err == nil {
+		switch r.Method {
+		case http.MethodGet:
+			dh.getStats(w, r)
+		case http.MethodOptions:
+			dh.Options(w, r)
+		default:
+			writeMethodNotAllowed(w, r, diaryMethods)
+		}
+
+		return
+	}
+
+	_, entryID, err := utils.GetDiaryIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrDiaryEntryNotExists)
+		return
+	}
+
+	isCollectionPath := entryID == ""
+
+	switch {
+	case r.Method == http.MethodOptions:
+		dh.Options(w, r)
+	case isCollectionPath && r.Method == http.MethodGet:
+		dh.getEntries(w, r)
+	case isCollectionPath && r.Method == http.MethodPost:
+		dh.postEntry(w, r)
+	case !isCollectionPath && r.Method == http.MethodGet:
+		dh.getEntry(w, r)
+	case !isCollectionPath && r.Method == http.MethodDelete:
+		dh.deleteEntry(w, r)
+	default:
+		writeMethodNotAllowed(w, r, diaryMethods)
+	}
+}
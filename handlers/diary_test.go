@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"moviepin/auth"
+	"moviepin/db/diary"
+	"moviepin/db/reviews"
+	"moviepin/mocks"
+	"moviepin/models"
+)
+
+const (
+	diaryPath      = "/users/a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11/diary"
+	diaryEntryPath = diaryPath + "/f5dd0166-6b5e-4ef8-bb6d-6bb9bd380a16"
+	diaryStatsPath = diaryPath + "/stats"
+)
+
+func TestDiaryPeriod(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{query: "year=2024", wantFrom: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), wantTo: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{query: "year=2024&month=2", wantFrom: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), wantTo: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{query: "year=2024&month=12", wantFrom: time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC), wantTo: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{query: "", wantErr: true},
+		{query: "year=0", wantErr: true},
+		{query: "year=2024&month=13", wantErr: true},
+		{query: "year=2024&month=march", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, diaryPath+"?"+test.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			from, to, err := diaryPeriod(req)
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+
+			if !from.Equal(test.wantFrom) || !to.Equal(test.wantTo) {
+				t.Errorf("got %v to %v, want %v to %v", from, to, test.wantFrom, test.wantTo)
+			}
+		})
+	}
+}
+
+func TestPostEntry(t *testing.T) {
+	handler := NewDiaryHandler(mocks.NewDiaryRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+	body := `{"movie_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","watched_on":"2024-03-02T23:30:00-05:00","rewatch":true,"notes":"Still holds up"}`
+
+	req, err := http.NewRequest(http.MethodPost, diaryPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	handler.postEntry(rr, withUser(req, &mocks.User))
+
+	assertStatusCode(t, rr.Code, http.StatusCreated)
+
+	var entry models.DiaryEntry
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.UserID != mocks.User.ID || !entry.Rewatch || entry.Notes != "Still holds up" {
+		t.Errorf("wrong entry, got %+v", entry)
+	}
+
+	if want := time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC); !entry.WatchedOn.Equal(want) {
+		t.Errorf("got watched on %v, want %v", entry.WatchedOn, want)
+	}
+
+	if got, want := rr.Header().Get("Location"), diaryPath+"/"+entry.ID.String(); got != want {
+		t.Errorf("got Location %q, want %q", got, want)
+	}
+}
+
+func TestDiaryServeHTTP(t *testing.T) {
+	entry := `{"movie_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","watched_on":"2024-03-02T00:00:00Z"}`
+	reviewedEntry := `{"movie_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","watched_on":"2024-03-02T00:00:00Z","review_id":"b2c3d4e5-f6a7-4b8c-9d0e-1f2a3b4c5d6e"}`
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		user   *models.User
+		setup  func(diary *mocks.DiaryRepository, reviews *mocks.ReviewsRepository)
+		want   int
+	}{
+		{name: "get diary", method: http.MethodGet, path: diaryPath + "?year=2024&month=3", user: &mocks.User, want: http.StatusOK},
+		{name: "get diary without period", method: http.MethodGet, path: diaryPath, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "get diary without user", method: http.MethodGet, path: diaryPath + "?year=2024", want: http.StatusUnauthorized},
+		{name: "get diary of someone else", method: http.MethodGet, path: diaryPath + "?year=2024", user: &mocks.Editor, want: http.StatusForbidden},
+		{name: "get diary invalid user", method: http.MethodGet, path: "/users/1/diary?year=2024", user: &mocks.User, want: http.StatusBadRequest},
+		{name: "get diary error", method: http.MethodGet, path: diaryPath + "?year=2024", user: &mocks.User, setup: func(d *mocks.DiaryRepository, _ *mocks.ReviewsRepository) { d.GetEntriesError = errors.New("error") }, want: http.StatusInternalServerError},
+		{name: "get entry", method: http.MethodGet, path: diaryEntryPath, user: &mocks.User, want: http.StatusOK},
+		{name: "get entry not found", method: http.MethodGet, path: diaryEntryPath, user: &mocks.User, setup: func(d *mocks.DiaryRepository, _ *mocks.ReviewsRepository) { d.GetEntryError = diary.ErrNotExists }, want: http.StatusNotFound},
+		{name: "get entry invalid id", method: http.MethodGet, path: diaryPath + "/1", user: &mocks.User, want: http.StatusBadRequest},
+		{name: "post entry", method: http.MethodPost, path: diaryPath, body: entry, user: &mocks.User, want: http.StatusCreated},
+		{name: "post entry without date", method: http.MethodPost, path: diaryPath, body: `{"movie_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "post entry without user", method: http.MethodPost, path: diaryPath, body: entry, want: http.StatusUnauthorized},
+		{name: "post entry to someone else's diary", method: http.MethodPost, path: diaryPath, body: entry, user: &mocks.Editor, want: http.StatusForbidden},
+		{name: "post entry missing movie", method: http.MethodPost, path: diaryPath, body: entry, user: &mocks.User, setup: func(d *mocks.DiaryRepository, _ *mocks.ReviewsRepository) { d.AddEntryError = diary.ErrMovieNotExists }, want: http.StatusNotFound},
+		{name: "post entry with review", method: http.MethodPost, path: diaryPath, body: reviewedEntry, user: &mocks.User, want: http.StatusCreated},
+		{name: "post entry missing review", method: http.MethodPost, path: diaryPath, body: reviewedEntry, user: &mocks.User, setup: func(_ *mocks.DiaryRepository, r *mocks.ReviewsRepository) { r.GetReviewError = reviews.ErrNotExists }, want: http.StatusNotFound},
+		{name: "put entry", method: http.MethodPut, path: diaryEntryPath, user: &mocks.User, want: http.StatusMethodNotAllowed},
+		{name: "delete entry", method: http.MethodDelete, path: diaryEntryPath, user: &mocks.User, want: http.StatusNoContent},
+		{name: "delete entry not found", method: http.MethodDelete, path: diaryEntryPath, user: &mocks.User, setup: func(d *mocks.DiaryRepository, _ *mocks.ReviewsRepository) { d.DeleteEntryError = diary.ErrNotExists }, want: http.StatusNotFound},
+		{name: "delete diary", method: http.MethodDelete, path: diaryPath, user: &mocks.User, want: http.StatusMethodNotAllowed},
+		{name: "get stats", method: http.MethodGet, path: diaryStatsPath, user: &mocks.User, want: http.StatusOK},
+		{name: "get stats of a year", method: http.MethodGet, path: diaryStatsPath + "?year=2024&limit=5", user: &mocks.User, want: http.StatusOK},
+		{name: "get stats invalid limit", method: http.MethodGet, path: diaryStatsPath + "?limit=51", user: &mocks.User, want: http.StatusBadRequest},
+		{name: "get stats of someone else", method: http.MethodGet, path: diaryStatsPath, user: &mocks.Editor, want: http.StatusForbidden},
+		{name: "delete stats", method: http.MethodDelete, path: diaryStatsPath, user: &mocks.User, want: http.StatusMethodNotAllowed},
+		{name: "options", method: http.MethodOptions, path: diaryPath, want: http.StatusNoContent},
+		{name: "unknown path", method: http.MethodGet, path: diaryEntryPath + "/notes", user: &mocks.User, want: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diaryRepo := mocks.NewDiaryRepository()
+			reviewsRepo := mocks.NewReviewsRepository()
+
+			if test.setup != nil {
+				test.setup(&diaryRepo, &reviewsRepo)
+			}
+
+			handler := NewDiaryHandler(diaryRepo, reviewsRepo, auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.user != nil {
+				req = withUser(req, test.user)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assertStatusCode(t, rr.Code, test.want)
+
+			if test.want == http.StatusMethodNotAllowed {
+				assertAllow(t, rr, diaryMethods)
+			}
+		})
+	}
+}
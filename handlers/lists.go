@@ -0,0 +1,860 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"moviepin/auth"
+	"moviepin/db/lists"
+	"moviepin/db/movies"
+	"moviepin/models"
+	"moviepin/patch"
+	"moviepin/problem"
+	"moviepin/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+const (
+	// ErrListNotExists is returned when list does not exist.
+	ErrListNotExists = "list does not exist"
+
+	// ErrListItemNotExists is returned when list item does not exist.
+	ErrListItemNotExists = "list item does not exist"
+
+	// ErrFailedToGetLists is returned when failed to get lists.
+	ErrFailedToGetLists = "failed to get lists"
+
+	// ErrFailedToGetList is returned when failed to get list.
+	ErrFailedToGetList = "failed to get list"
+
+	// ErrFailedToAddList is returned when failed to add list.
+	ErrFailedToAddList = "failed to add list"
+
+	// ErrFailedToDeleteList is returned when failed to delete list.
+	ErrFailedToDeleteList = "failed to delete list"
+
+	// ErrFailedToAddListItem is returned when failed to add movie to list.
+	ErrFailedToAddListItem = "failed to add movie to list"
+
+	// ErrFailedToDeleteListItem is returned when failed to remove movie from list.
+	ErrFailedToDeleteListItem = "failed to remove movie from list"
+
+	// ErrFailedToMoveListItem is returned when failed to move movie in list.
+	ErrFailedToMoveListItem = "failed to move movie in list"
+
+	// ErrFailedToOrderList is returned when failed to reorder list.
+	ErrFailedToOrderList = "failed to reorder list"
+
+	// ErrInvalidMove is returned when a move does not say where to exactly.
+	ErrInvalidMove = "move needs exactly one of before, after or index"
+
+	// ErrMovieAlreadyInList is returned when the movie added is already in the list.
+	ErrMovieAlreadyInList = "movie is already in the list"
+
+	// ErrFailedToUpdateList is returned when failed to update list.
+	ErrFailedToUpdateList = "failed to update list"
+
+	// ErrSlugTaken is returned when the owner of a list has another list with its slug.
+	ErrSlugTaken = "slug is already used by another list"
+
+	// ErrNotListOwner is returned when user changes a list of someone else.
+	ErrNotListOwner = "forbidden: only the owner can change a list"
+)
+
+const (
+	// Slug of lists whose title has no letters or digits to make one of.
+	defaultSlug = "list"
+
+	// Largest number added to a slug made from a title to tell it apart.
+	maxSlugNumber = 20
+)
+
+type ListsHandler struct {
+	lists    lists.ListsRepository
+	movies   movies.MoviesRepository
+	policy   *auth.Policy
+	logger   *slog.Logger
+	validate *validator.Validate
+}
+
+// Returns a new ListsHandler.
+func NewListsHandler(lists lists.ListsRepository, movies movies.MoviesRepository, policy *auth.Policy, logger *slog.Logger, validate *validator.Validate) *ListsHandler {
+	return &ListsHandler{lists: lists, movies: movies, policy: policy, logger: logger, validate: validate}
+}
+
+// Responds with the lists of a user, without their items. Others than the
+// user only get the public ones.
+func (lh ListsHandler) getLists(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.GetUserIDFromListsPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	if err = lh.validate.Var(userID, "required,uuid"); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToGetLists, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToGetLists, problem.FieldErrors(err, "user_id"))
+		return
+	}
+
+	user, authenticated := auth.UserFromContext(r.Context())
+	publicOnly := !authenticated || user.ID.String() != userID
+
+	userLists, err := lh.lists.GetLists(r.Context(), userID, publicOnly)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToGetLists, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetLists)
+		return
+	}
+
+	listsJson, err := json.Marshal(userLists)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToGetLists, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetLists)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(listsJson)
+}
+
+// Creates a list sent in request for the user in path, who must be the caller.
+// Lists are private unless the request says otherwise, and get a slug made
+// from their title unless it sends one.
+func (lh ListsHandler) postList(w http.ResponseWriter, r *http.Request) {
+	user, ok := authorize(w, r, lh.policy, auth.PermWriteLists)
+
+	if !ok {
+		return
+	}
+
+	userID, err := utils.GetUserIDFromListsPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	if err = lh.validate.Var(userID, "required,uuid"); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToAddList, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToAddList, problem.FieldErrors(err, "user_id"))
+		return
+	}
+
+	if userID != user.ID.String() {
+		problem.Write(w, http.StatusForbidden, ErrNotListOwner)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToAddList, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddList)
+		return
+	}
+
+	var list models.List
+
+	if err = json.Unmarshal(body, &list); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToAddList, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddList, err)
+		return
+	}
+
+	// Fields managed by the server are never taken from the request.
+	now := time.Now().UTC()
+	list.ID = uuid.New()
+	list.UserID = user.ID
+	list.CreatedAt = now
+	list.UpdatedAt = now
+
+	if list.Visibility == "" {
+		list.Visibility = models.ListVisibilityPrivate
+	}
+
+	slugFromTitle := list.Slug == ""
+
+	// Leaves room for the number added when the slug is taken.
+	baseSlug := utils.Slugify(list.Title, utils.MaxSlugLength-3)
+
+	if baseSlug == "" {
+		baseSlug = defaultSlug
+	}
+
+	if slugFromTitle {
+		list.Slug = baseSlug
+	}
+
+	if err = lh.validate.Struct(list); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToAddList, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddList, err)
+		return
+	}
+
+	err = lh.lists.AddList(r.Context(), list)
+
+	// A slug made from the title is numbered when the owner has a list with
+	// it already.
+	for n := 2; err == lists.ErrDuplicateSlug && slugFromTitle && n <= maxSlugNumber; n++ {
+		list.Slug = baseSlug + "-" + strconv.Itoa(n)
+		err = lh.lists.AddList(r.Context(), list)
+	}
+
+	if err == lists.ErrDuplicateSlug {
+		problem.Write(w, http.StatusConflict, ErrSlugTaken)
+		return
+	}
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToAddList, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddList)
+		return
+	}
+
+	listJson, err := json.Marshal(list)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToAddList, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddList)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/lists/"+list.ID.String())
+	w.WriteHeader(http.StatusCreated)
+	w.Write(listJson)
+}
+
+// Responds with a list and its movies in order.
+func (lh ListsHandler) getList(w http.ResponseWriter, r *http.Request) {
+	listID, err := utils.GetListIDFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	list, ok := lh.findList(w, r, listID, ErrFailedToGetList)
+
+	if !ok {
+		return
+	}
+
+	lh.writeListDetails(w, r, list)
+}
+
+// Responds with a list and its movies in order, found by the name of its
+// owner and its slug as in /l/{username}/{slug}.
+func (lh ListsHandler) getSharedList(w http.ResponseWriter, r *http.Request) {
+	username, slug, err := utils.GetListSlugFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	list, err := lh.lists.GetListBySlug(r.Context(), username, slug)
+
+	if err == lists.ErrNotExists {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToGetList, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetList)
+		return
+	}
+
+	if !canReadList(r, list) {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	lh.writeListDetails(w, r, list)
+}
+
+// Responds with list and its movies in order.
+func (lh ListsHandler) writeListDetails(w http.ResponseWriter, r *http.Request, list *models.List) {
+	items, err := lh.lists.GetListItems(r.Context(), list.ID.String())
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToGetList, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetList)
+		return
+	}
+
+	listJson, err := json.Marshal(models.ListDetails{List: *list, Items: items})
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToGetList, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetList)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(listJson)
+}
+
+// Updates the title, description, visibility or slug of a list of the caller
+// with a patch sent in request.
+func (lh ListsHandler) patchList(w http.ResponseWriter, r *http.Request) {
+	listID, err := utils.GetListIDFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	list, ok := lh.ownedList(w, r, listID, ErrFailedToUpdateList)
+
+	if !ok {
+		return
+	}
+
+	contentType, ok := patchContentType(r)
+
+	if !ok {
+		w.Header().Set("Accept-Patch", acceptPatch)
+		problem.Write(w, http.StatusUnsupportedMediaType, ErrUnsupportedPatch)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToUpdateList, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateList)
+		return
+	}
+
+	patched, err := applyPatch(*list, contentType, body)
+
+	if err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToUpdateList, "error", err)
+
+		// The document is fine, the list is not in the state it expects.
+		if errors.Is(err, patch.ErrTestFailed) {
+			problem.Write(w, http.StatusConflict, ErrFailedToUpdateList+": "+err.Error())
+			return
+		}
+
+		problem.WriteInvalid(w, ErrFailedToUpdateList, err)
+		return
+	}
+
+	// Fields managed by the server are never taken from the request.
+	patched.ID = list.ID
+	patched.UserID = list.UserID
+	patched.CreatedAt = list.CreatedAt
+	patched.UpdatedAt = time.Now().UTC()
+
+	if err = lh.validate.Struct(patched); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToUpdateList, "error", err)
+		problem.WriteInvalid(w, ErrFailedToUpdateList, err)
+		return
+	}
+
+	if err = lh.lists.UpdateList(r.Context(), patched); err != nil {
+		switch err {
+		case lists.ErrNotExists:
+			problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		case lists.ErrDuplicateSlug:
+			problem.Write(w, http.StatusConflict, ErrSlugTaken)
+		default:
+			lh.logger.ErrorContext(r.Context(), ErrFailedToUpdateList, "error", err)
+			problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateList)
+		}
+
+		return
+	}
+
+	listJson, err := json.Marshal(patched)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToUpdateList, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateList)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(listJson)
+}
+
+// Deletes a list of the caller with all its items.
+func (lh ListsHandler) deleteList(w http.ResponseWriter, r *http.Request) {
+	listID, err := utils.GetListIDFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	if _, ok := lh.ownedList(w, r, listID, ErrFailedToDeleteList); !ok {
+		return
+	}
+
+	if err = lh.lists.DeleteList(r.Context(), listID); err != nil {
+		if err == lists.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrListNotExists)
+			return
+		}
+
+		lh.logger.ErrorContext(r.Context(), ErrFailedToDeleteList, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToDeleteList)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Responds with the items of a list and their movies in order.
+func (lh ListsHandler) getListItems(w http.ResponseWriter, r *http.Request) {
+	listID, _, err := utils.GetListItemIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	if _, ok := lh.findList(w, r, listID, ErrFailedToGetList); !ok {
+		return
+	}
+
+	lh.writeListItems(w, r, listID, ErrFailedToGetList)
+}
+
+// Appends the movie sent in request to a list of the caller.
+func (lh ListsHandler) postListItem(w http.ResponseWriter, r *http.Request) {
+	listID, _, err := utils.GetListItemIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	list, ok := lh.ownedList(w, r, listID, ErrFailedToAddListItem)
+
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToAddListItem, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddListItem)
+		return
+	}
+
+	var item models.ListItem
+
+	if err = json.Unmarshal(body, &item); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToAddListItem, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddListItem, err)
+		return
+	}
+
+	if err = lh.validate.Struct(item); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToAddListItem, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddListItem, err)
+		return
+	}
+
+	movie, err := lh.movies.GetMovie(r.Context(), item.MovieID.String())
+
+	if err != nil {
+		if err == movies.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrNotExists)
+			return
+		}
+
+		lh.logger.ErrorContext(r.Context(), ErrFailedToAddListItem, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddListItem)
+		return
+	}
+
+	// Fields managed by the server are never taken from the request.
+	item.ID = uuid.New()
+	item.ListID = list.ID
+	item.Movie = movie
+
+	item.Position, err = lh.lists.AddListItem(r.Context(), item)
+
+	if err != nil {
+		switch err {
+		case lists.ErrNotExists:
+			problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		case lists.ErrDuplicateMovie:
+			problem.Write(w, http.StatusConflict, ErrMovieAlreadyInList)
+		default:
+			lh.logger.ErrorContext(r.Context(), ErrFailedToAddListItem, "error", err)
+			problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddListItem)
+		}
+
+		return
+	}
+
+	itemJson, err := json.Marshal(item)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToAddListItem, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddListItem)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(itemJson)
+}
+
+// Removes an item from a list of the caller.
+func (lh ListsHandler) deleteListItem(w http.ResponseWriter, r *http.Request) {
+	listID, itemID, err := utils.GetListItemIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListItemNotExists)
+		return
+	}
+
+	if err = lh.validate.Var(itemID, "required,uuid"); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToDeleteListItem, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToDeleteListItem, problem.FieldErrors(err, "item_id"))
+		return
+	}
+
+	if _, ok := lh.ownedList(w, r, listID, ErrFailedToDeleteListItem); !ok {
+		return
+	}
+
+	if err = lh.lists.DeleteListItem(r.Context(), listID, itemID); err != nil {
+		switch err {
+		case lists.ErrNotExists:
+			problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		case lists.ErrItemNotExists:
+			problem.Write(w, http.StatusNotFound, ErrListItemNotExists)
+		default:
+			lh.logger.ErrorContext(r.Context(), ErrFailedToDeleteListItem, "error", err)
+			problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToDeleteListItem)
+		}
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Moves an item of a list of the caller before or after another item or to
+// an index, then responds with the items in their new order.
+func (lh ListsHandler) moveListItem(w http.ResponseWriter, r *http.Request) {
+	listID, itemID, err := utils.GetListItemIDsFromMovePath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListItemNotExists)
+		return
+	}
+
+	if err = lh.validate.Var(itemID, "required,uuid"); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToMoveListItem, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToMoveListItem, problem.FieldErrors(err, "item_id"))
+		return
+	}
+
+	if _, ok := lh.ownedList(w, r, listID, ErrFailedToMoveListItem); !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToMoveListItem, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToMoveListItem)
+		return
+	}
+
+	var move models.ListItemMove
+
+	if err = json.Unmarshal(body, &move); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToMoveListItem, "error", err)
+		problem.WriteInvalid(w, ErrFailedToMoveListItem, err)
+		return
+	}
+
+	if !validMove(move) {
+		problem.Write(w, http.StatusBadRequest, ErrInvalidMove)
+		return
+	}
+
+	if _, err = lh.lists.MoveListItem(r.Context(), listID, itemID, move); err != nil {
+		switch err {
+		case lists.ErrNotExists:
+			problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		case lists.ErrItemNotExists:
+			problem.Write(w, http.StatusNotFound, ErrListItemNotExists)
+		case lists.ErrAnchorNotExists, lists.ErrInvalidPosition:
+			problem.WriteInvalid(w, ErrFailedToMoveListItem, err)
+		default:
+			lh.logger.ErrorContext(r.Context(), ErrFailedToMoveListItem, "error", err)
+			problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToMoveListItem)
+		}
+
+		return
+	}
+
+	lh.writeListItems(w, r, listID, ErrFailedToMoveListItem)
+}
+
+// Returns whether move sets exactly one place to move to.
+func validMove(move models.ListItemMove) bool {
+	places := 0
+
+	if move.Before != nil {
+		places++
+	}
+
+	if move.After != nil {
+		places++
+	}
+
+	if move.Index != nil {
+		places++
+	}
+
+	return places == 1
+}
+
+// Puts the items of a list of the caller in the order of the item ids sent
+// in request, then responds with the items in that order.
+func (lh ListsHandler) putListOrder(w http.ResponseWriter, r *http.Request) {
+	listID, _, err := utils.GetListItemIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return
+	}
+
+	if _, ok := lh.ownedList(w, r, listID, ErrFailedToOrderList); !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), ErrFailedToOrderList, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToOrderList)
+		return
+	}
+
+	var itemIDs []uuid.UUID
+
+	if err = json.Unmarshal(body, &itemIDs); err != nil {
+		lh.logger.InfoContext(r.Context(), ErrFailedToOrderList, "error", err)
+		problem.WriteInvalid(w, ErrFailedToOrderList, err)
+		return
+	}
+
+	if err = lh.lists.SetListOrder(r.Context(), listID, itemIDs); err != nil {
+		switch err {
+		case lists.ErrNotExists:
+			problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		case lists.ErrOrderMismatch:
+			problem.WriteInvalid(w, ErrFailedToOrderList, err)
+		default:
+			lh.logger.ErrorContext(r.Context(), ErrFailedToOrderList, "error", err)
+			problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToOrderList)
+		}
+
+		return
+	}
+
+	lh.writeListItems(w, r, listID, ErrFailedToOrderList)
+}
+
+// Responds with the items of a list and their movies in order, writing a
+// problem with detail when they cannot be read.
+func (lh ListsHandler) writeListItems(w http.ResponseWriter, r *http.Request, listID string, detail string) {
+	items, err := lh.lists.GetListItems(r.Context(), listID)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), detail, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), detail)
+		return
+	}
+
+	itemsJson, err := json.Marshal(items)
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), detail, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), detail)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(itemsJson)
+}
+
+// Returns the list with listID, writing a problem with detail and returning
+// false when it is invalid, missing or cannot be read. Private lists of others
+// than the caller are missing, so that their ids do not leak.
+func (lh ListsHandler) findList(w http.ResponseWriter, r *http.Request, listID string, detail string) (*models.List, bool) {
+	if err := lh.validate.Var(listID, "required,uuid"); err != nil {
+		lh.logger.InfoContext(r.Context(), detail, "error", err)
+		problem.WriteFieldErrors(w, detail, problem.FieldErrors(err, "list_id"))
+		return nil, false
+	}
+
+	list, err := lh.lists.GetList(r.Context(), listID)
+
+	if err == lists.ErrNotExists {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return nil, false
+	}
+
+	if err != nil {
+		lh.logger.ErrorContext(r.Context(), detail, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), detail)
+		return nil, false
+	}
+
+	if !canReadList(r, list) {
+		problem.Write(w, http.StatusNotFound, ErrListNotExists)
+		return nil, false
+	}
+
+	return list, true
+}
+
+// Reports whether the caller can read list: anyone can read lists that are
+// not private, only their owner can read the others.
+func canReadList(r *http.Request, list *models.List) bool {
+	if list.Visibility != models.ListVisibilityPrivate {
+		return true
+	}
+
+	user, ok := auth.UserFromContext(r.Context())
+
+	return ok && user.ID == list.UserID
+}
+
+// Like findList, but also requires the caller to be allowed to write lists
+// and to own the list.
+func (lh ListsHandler) ownedList(w http.ResponseWriter, r *http.Request, listID string, detail string) (*models.List, bool) {
+	user, ok := authorize(w, r, lh.policy, auth.PermWriteLists)
+
+	if !ok {
+		return nil, false
+	}
+
+	list, ok := lh.findList(w, r, listID, detail)
+
+	if !ok {
+		return nil, false
+	}
+
+	if list.UserID != user.ID {
+		problem.Write(w, http.StatusForbidden, ErrNotListOwner)
+		return nil, false
+	}
+
+	return list, true
+}
+
+// Methods the lists routes allow, advertised by Options and 405 responses.
+const listsMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+
+// Responds with allowed methods.
+func (lh ListsHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Methods", listsMethods)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Accept-Patch", acceptPatch)
+	w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (lh ListsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		lh.Options(w, r)
+		return
+	}
+
+	// Reading lists that are not private is public, changing them is up to
+	// their owner.
+	if _, _, err := utils.GetListSlugFromPath(r.URL.Path); err == nil {
+		if r.Method == http.MethodGet {
+			lh.getSharedList(w, r)
+		} else {
+			writeMethodNotAllowed(w, r, listsMethods)
+		}
+
+		return
+	}
+
+	if _, err := utils.GetUserIDFromListsPath(r.URL.Path); err == nil {
+		switch r.Method {
+		case http.MethodGet:
+			lh.getLists(w, r)
+		case http.MethodPost:
+			lh.postList(w, r)
+		default:
+			writeMethodNotAllowed(w, r, listsMethods)
+		}
+
+		return
+	}
+
+	if _, _, err := utils.GetListItemIDsFromMovePath(r.URL.Path); err == nil {
+		if r.Method == http.MethodPost {
+			lh.moveListItem(w, r)
+		} else {
+			writeMethodNotAllowed(w, r, listsMethods)
+		}
+
+		return
+	}
+
+	if _, itemID, err := utils.GetListItemIDsFromPath(r.URL.Path); err == nil {
+		switch {
+		case itemID == "" && r.Method == http.MethodGet:
+			lh.getListItems(w, r)
+		case itemID == "" && r.Method == http.MethodPost:
+			lh.postListItem(w, r)
+		case itemID == "" && r.Method == http.MethodPut:
+			lh.putListOrder(w, r)
+		case itemID != "" && r.Method == http.MethodDelete:
+			lh.deleteListItem(w, r)
+		default:
+			writeMethodNotAllowed(w, r, listsMethods)
+		}
+
+		return
+	}
+
+	if _, err := utils.GetListIDFromPath(r.URL.Path); err == nil {
+		switch r.Method {
+		case http.MethodGet:
+			lh.getList(w, r)
+		case http.MethodPatch:
+			lh.patchList(w, r)
+		case http.MethodDelete:
+			lh.deleteList(w, r)
+		default:
+			writeMethodNotAllowed(w, r, listsMethods)
+		}
+
+		return
+	}
+
+	problem.Write(w, http.StatusNotFound, ErrListNotExists)
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"moviepin/auth"
+	"moviepin/db/lists"
+	"moviepin/db/movies"
+	"moviepin/mocks"
+	"moviepin/models"
+)
+
+const (
+	userListsPath = "/users/a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11/lists"
+	listPath      = "/lists/d3bbef66-6f3c-4ef8-bb6d-6bb9bd380a14"
+	listItemsPath = listPath + "/items"
+	listItemPath  = listItemsPath + "/e4ccf055-5a4d-4ef8-bb6d-6bb9bd380a15"
+	listMovePath  = listItemPath + "/move"
+)
+
+func TestGetList(t *testing.T) {
+	handler := NewListsHandler(mocks.NewListsRepository(), mocks.NewMoviesRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+	req, err := http.NewRequest("GET", listPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	handler.getList(rr, req)
+
+	assertStatusCode(t, rr.Code, http.StatusOK)
+
+	var list models.ListDetails
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+
+	if list.ID != mocks.List.ID || list.Title != mocks.List.Title {
+		t.Errorf("wrong list, got %+v want %+v", list.List, mocks.List)
+	}
+
+	if len(list.Items) != 1 || list.Items[0].Movie == nil || list.Items[0].Movie.Title != mocks.Movie.Title {
+		t.Errorf("list items are not hydrated, got %+v", list.Items)
+	}
+}
+
+func TestPostList(t *testing.T) {
+	handler := NewListsHandler(mocks.NewListsRepository(), mocks.NewMoviesRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+	tests := []struct {
+		name           string
+		body           string
+		wantVisibility string
+		wantSlug       string
+	}{
+		{name: "defaults", body: `{"title":"Rainy day: Part 2"}`, wantVisibility: models.ListVisibilityPrivate, wantSlug: "rainy-day-part-2"},
+		{name: "no letters in title", body: `{"title":"???"}`, wantVisibility: models.ListVisibilityPrivate, wantSlug: "list"},
+		{name: "chosen", body: `{"title":"Rainy day","visibility":"unlisted","slug":"wet"}`, wantVisibility: models.ListVisibilityUnlisted, wantSlug: "wet"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, userListsPath, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.postList(rr, withUser(req, &mocks.User))
+
+			assertStatusCode(t, rr.Code, http.StatusCreated)
+
+			var list models.List
+
+			if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+				t.Fatal(err)
+			}
+
+			if list.Visibility != test.wantVisibility || list.Slug != test.wantSlug {
+				t.Errorf("got visibility %q and slug %q, want %q and %q", list.Visibility, list.Slug, test.wantVisibility, test.wantSlug)
+			}
+		})
+	}
+}
+
+func TestCanReadList(t *testing.T) {
+	tests := []struct {
+		visibility string
+		user       *models.User
+		want       bool
+	}{
+		{visibility: models.ListVisibilityPublic, want: true},
+		{visibility: models.ListVisibilityUnlisted, want: true},
+		{visibility: models.ListVisibilityPrivate, want: false},
+		{visibility: models.ListVisibilityPrivate, user: &mocks.Admin, want: false},
+		{visibility: models.ListVisibilityPrivate, user: &mocks.User, want: true},
+	}
+
+	for _, test := range tests {
+		name := test.visibility
+
+		if test.user != nil {
+			name += " " + test.user.Username
+		}
+
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, listPath, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.user != nil {
+				req = withUser(req, test.user)
+			}
+
+			list := mocks.List
+			list.Visibility = test.visibility
+
+			if got := canReadList(req, &list); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestListsServeHTTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		user        *models.User
+		contentType string
+		setup       func(lists *mocks.ListsRepository, movies *mocks.MoviesRepository)
+		want        int
+	}{
+		{name: "get lists", method: http.MethodGet, path: userListsPath, want: http.StatusOK},
+		{name: "get lists invalid user", method: http.MethodGet, path: "/users/1/lists", want: http.StatusBadRequest},
+		{name: "get lists error", method: http.MethodGet, path: userListsPath, setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) { l.GetListsError = errors.New("error") }, want: http.StatusInternalServerError},
+		{name: "post list", method: http.MethodPost, path: userListsPath, body: `{"title":"Rainy day"}`, user: &mocks.User, want: http.StatusCreated},
+		{name: "post list without user", method: http.MethodPost, path: userListsPath, body: `{"title":"Rainy day"}`, want: http.StatusUnauthorized},
+		{name: "post list for someone else", method: http.MethodPost, path: userListsPath, body: `{"title":"Rainy day"}`, user: &mocks.Editor, want: http.StatusForbidden},
+		{name: "post list without title", method: http.MethodPost, path: userListsPath, body: `{"description":"none"}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "get list not found", method: http.MethodGet, path: listPath, setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) { l.GetListError = lists.ErrNotExists }, want: http.StatusNotFound},
+		{name: "get list invalid id", method: http.MethodGet, path: "/lists/1", want: http.StatusBadRequest},
+		{name: "put list", method: http.MethodPut, path: listPath, user: &mocks.User, want: http.StatusMethodNotAllowed},
+		{name: "delete list", method: http.MethodDelete, path: listPath, user: &mocks.User, want: http.StatusNoContent},
+		{name: "delete list of someone else", method: http.MethodDelete, path: listPath, user: &mocks.Admin, want: http.StatusForbidden},
+		{name: "get list items", method: http.MethodGet, path: listItemsPath, want: http.StatusOK},
+		{name: "post list item", method: http.MethodPost, path: listItemsPath, body: `{"movie_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}`, user: &mocks.User, want: http.StatusCreated},
+		{name: "post list item without movie", method: http.MethodPost, path: listItemsPath, body: `{}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "post list item missing movie", method: http.MethodPost, path: listItemsPath, body: `{"movie_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}`, user: &mocks.User, setup: func(_ *mocks.ListsRepository, m *mocks.MoviesRepository) { m.GetMovieError = movies.ErrNotExists }, want: http.StatusNotFound},
+		{name: "post list item twice", method: http.MethodPost, path: listItemsPath, body: `{"movie_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}`, user: &mocks.User, setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) {
+			l.AddListItemError = lists.ErrDuplicateMovie
+		}, want: http.StatusConflict},
+		{name: "post list item to someone else's list", method: http.MethodPost, path: listItemsPath, body: `{"movie_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}`, user: &mocks.Editor, want: http.StatusForbidden},
+		{name: "delete list item", method: http.MethodDelete, path: listItemPath, user: &mocks.User, want: http.StatusNoContent},
+		{name: "delete list item not found", method: http.MethodDelete, path: listItemPath, user: &mocks.User, setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) {
+			l.DeleteListItemError = lists.ErrItemNotExists
+		}, want: http.StatusNotFound},
+		{name: "delete list items", method: http.MethodDelete, path: listItemsPath, user: &mocks.User, want: http.StatusMethodNotAllowed},
+		{name: "move list item", method: http.MethodPost, path: listMovePath, body: `{"index":0}`, user: &mocks.User, want: http.StatusOK},
+		{name: "move list item nowhere", method: http.MethodPost, path: listMovePath, body: `{}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "move list item two ways", method: http.MethodPost, path: listMovePath, body: `{"index":0,"after":"e4ccf055-5a4d-4ef8-bb6d-6bb9bd380a16"}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "move list item past end", method: http.MethodPost, path: listMovePath, body: `{"index":5}`, user: &mocks.User, setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) {
+			l.MoveListItemError = lists.ErrInvalidPosition
+		}, want: http.StatusBadRequest},
+		{name: "move list item unknown anchor", method: http.MethodPost, path: listMovePath, body: `{"before":"e4ccf055-5a4d-4ef8-bb6d-6bb9bd380a16"}`, user: &mocks.User, setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) {
+			l.MoveListItemError = lists.ErrAnchorNotExists
+		}, want: http.StatusBadRequest},
+		{name: "move list item not found", method: http.MethodPost, path: listMovePath, body: `{"index":0}`, user: &mocks.User, setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) {
+			l.MoveListItemError = lists.ErrItemNotExists
+		}, want: http.StatusNotFound},
+		{name: "move list item in someone else's list", method: http.MethodPost, path: listMovePath, body: `{"index":0}`, user: &mocks.Editor, want: http.StatusForbidden},
+		{name: "get list item move", method: http.MethodGet, path: listMovePath, want: http.StatusMethodNotAllowed},
+		{name: "put list order", method: http.MethodPut, path: listItemsPath, body: `["e4ccf055-5a4d-4ef8-bb6d-6bb9bd380a15"]`, user: &mocks.User, want: http.StatusOK},
+		{name: "put list order not an array", method: http.MethodPut, path: listItemsPath, body: `{"items":[]}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "put list order mismatch", method: http.MethodPut, path: listItemsPath, body: `[]`, user: &mocks.User, setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) {
+			l.SetListOrderError = lists.ErrOrderMismatch
+		}, want: http.StatusBadRequest},
+		{name: "put list order without user", method: http.MethodPut, path: listItemsPath, body: `[]`, want: http.StatusUnauthorized},
+		{name: "post list with invalid slug", method: http.MethodPost, path: userListsPath, body: `{"title":"Rainy day","slug":"Rainy Day"}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "post list with unknown visibility", method: http.MethodPost, path: userListsPath, body: `{"title":"Rainy day","visibility":"friends"}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "post list with slug taken", method: http.MethodPost, path: userListsPath, body: `{"title":"Rainy day"}`, user: &mocks.User, setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) {
+			l.AddListError = lists.ErrDuplicateSlug
+		}, want: http.StatusConflict},
+		{name: "patch list", method: http.MethodPatch, path: listPath, body: `{"visibility":"private","slug":"wet"}`, user: &mocks.User, want: http.StatusOK},
+		{name: "patch list with json patch", method: http.MethodPatch, path: listPath, body: `[{"op":"test","path":"/visibility","value":"private"}]`, user: &mocks.User, contentType: "application/json-patch+json", want: http.StatusConflict},
+		{name: "patch list unsupported", method: http.MethodPatch, path: listPath, body: `<list/>`, user: &mocks.User, contentType: "application/xml", want: http.StatusUnsupportedMediaType},
+		{name: "patch list invalid slug", method: http.MethodPatch, path: listPath, body: `{"slug":"wet day"}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "patch list slug taken", method: http.MethodPatch, path: listPath, body: `{"slug":"wet"}`, user: &mocks.User, setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) {
+			l.UpdateListError = lists.ErrDuplicateSlug
+		}, want: http.StatusConflict},
+		{name: "patch list of someone else", method: http.MethodPatch, path: listPath, body: `{"slug":"wet"}`, user: &mocks.Editor, want: http.StatusForbidden},
+		{name: "patch list without user", method: http.MethodPatch, path: listPath, body: `{"slug":"wet"}`, want: http.StatusUnauthorized},
+		{name: "get shared list", method: http.MethodGet, path: "/l/dummyuser1/rainy-day", want: http.StatusOK},
+		{name: "get shared list not found", method: http.MethodGet, path: "/l/dummyuser1/sunny-day", setup: func(l *mocks.ListsRepository, _ *mocks.MoviesRepository) {
+			l.GetListBySlugError = lists.ErrNotExists
+		}, want: http.StatusNotFound},
+		{name: "delete shared list", method: http.MethodDelete, path: "/l/dummyuser1/rainy-day", user: &mocks.User, want: http.StatusMethodNotAllowed},
+		{name: "options", method: http.MethodOptions, path: listItemsPath, want: http.StatusNoContent},
+		{name: "unknown path", method: http.MethodGet, path: "/lists/", want: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			listsRepo := mocks.NewListsRepository()
+			moviesRepo := mocks.NewMoviesRepository()
+
+			if test.setup != nil {
+				test.setup(&listsRepo, &moviesRepo)
+			}
+
+			handler := NewListsHandler(listsRepo, moviesRepo, auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+
+			if test.user != nil {
+				req = withUser(req, test.user)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assertStatusCode(t, rr.Code, test.want)
+
+			if test.want == http.StatusMethodNotAllowed {
+				assertAllow(t, rr, listsMethods)
+			}
+		})
+	}
+}
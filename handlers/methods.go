@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"net/http"
+
+	"moviepin/problem"
+)
+
+// Responds with 405 to the method of r, listing the allowed methods in Allow
+// as RFC 9110 asks. Allowed is the list Options of the route advertises.
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed string) {
+	w.Header().Set("Allow", allowed)
+	problem.Write(w, http.StatusMethodNotAllowed, "method "+r.Method+" is not allowed")
+}
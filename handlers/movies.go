@@ -1,14 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"moviepin/auth"
 	"moviepin/db/movies"
+	"moviepin/db/watchlist"
 	"moviepin/models"
+	"moviepin/patch"
+	"moviepin/problem"
 	"moviepin/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 )
 
 const (
@@ -27,6 +42,12 @@ const (
 	// ErrFailedToAddMovie is returned when failed to add movie.
 	ErrFailedToAddMovie = "failed to add movie"
 
+	// ErrInvalidBatchMode is returned when mode is not a known batch mode.
+	ErrInvalidBatchMode = "mode must be atomic or partial"
+
+	// ErrClientIDsNotAllowed is returned when ids are sent without opting in.
+	ErrClientIDsNotAllowed = "movie ids are assigned by the server, set client_ids=true to supply them"
+
 	// ErrFailedToUpdateMovie is returned when failed to update movie.
 	ErrFailedToUpdateMovie = "failed to update movie"
 
@@ -35,40 +56,183 @@ const (
 
 	// ErrFailedToReplaceMovies is returned when failed to replace movies.
 	ErrFailedToReplaceMovies = "failed to replace movies"
+
+	// ErrFailedToSearchMovies is returned when failed to search movies.
+	ErrFailedToSearchMovies = "failed to search movies"
+
+	// ErrFailedToAutocomplete is returned when failed to suggest titles.
+	ErrFailedToAutocomplete = "failed to autocomplete movies"
+)
+
+const (
+	// Longest search query or autocomplete prefix accepted.
+	maxSearchQueryLength = 200
+
+	// Number of suggestions returned when request does not set a limit.
+	defaultSuggestionLimit = 10
+
+	// Largest number of suggestions a request can ask for.
+	maxSuggestionLimit = 20
+
+	// Largest number of movies of a partial batch inserted at once.
+	maxConcurrentInserts = 8
 )
 
+// Ways to add a batch of movies, chosen with the mode query parameter.
+const (
+	// Either all movies are added or none is.
+	batchModeAtomic = "atomic"
+
+	// Valid movies are added even when others fail.
+	batchModePartial = "partial"
+)
+
+// Code of batch items failing validation, next to the database codes.
+const codeValidation = "validation"
+
+// Describes why a movie of a batch was not added.
+type movieFailure struct {
+	// Position of the movie in the request.
+	Index   int                  `json:"index"`
+	Code    string               `json:"code"`
+	Message string               `json:"message"`
+	Errors  []problem.FieldError `json:"errors,omitempty"`
+}
+
 type MoviesHandler struct {
-	db movies.MoviesRepository
+	db        movies.MoviesRepository
+	watchlist watchlist.WatchlistRepository
+	policy    *auth.Policy
+	logger    *slog.Logger
+	validate  *validator.Validate
 }
 
 // Returns a new MoviesHandler.
-func NewMoviesHandler(db movies.MoviesRepository) *MoviesHandler {
-	return &MoviesHandler{db: db}
+func NewMoviesHandler(db movies.MoviesRepository, watchlist watchlist.WatchlistRepository, policy *auth.Policy, logger *slog.Logger, validate *validator.Validate) *MoviesHandler {
+	return &MoviesHandler{db: db, watchlist: watchlist, policy: policy, logger: logger, validate: validate}
+}
+
+// Query parameters accepted when listing movies.
+var moviesQueryParams = map[string]bool{
+	"limit":             true,
+	"cursor":            true,
+	"sort":              true,
+	"genre":             true,
+	"director":          true,
+	"title_prefix":      true,
+	"release_date_from": true,
+	"release_date_to":   true,
+}
+
+// Returns movies query described by the query parameters of a request.
+// Release dates are inclusive and given as YYYY-MM-DD.
+func moviesQuery(r *http.Request) (movies.MoviesQuery, error) {
+	values := r.URL.Query()
+
+	for key := range values {
+		if !moviesQueryParams[key] {
+			return movies.MoviesQuery{}, fmt.Errorf("unknown query parameter %q", key)
+		}
+	}
+
+	limit, err := pageLimit(r)
+
+	if err != nil {
+		return movies.MoviesQuery{}, err
+	}
+
+	sort, err := movies.ParseSort(values.Get("sort"))
+
+	if err != nil {
+		return movies.MoviesQuery{}, err
+	}
+
+	query := movies.MoviesQuery{
+		Limit:  limit,
+		Cursor: values.Get("cursor"),
+		Sort:   sort,
+		Filter: movies.MoviesFilter{
+			Genre:       values.Get("genre"),
+			Director:    values.Get("director"),
+			TitlePrefix: values.Get("title_prefix"),
+		},
+	}
+
+	if from := values.Get("release_date_from"); from != "" {
+		if query.Filter.ReleasedFrom, err = time.Parse(time.DateOnly, from); err != nil {
+			return movies.MoviesQuery{}, err
+		}
+	}
+
+	if to := values.Get("release_date_to"); to != "" {
+		if query.Filter.ReleasedTo, err = time.Parse(time.DateOnly, to); err != nil {
+			return movies.MoviesQuery{}, err
+		}
+	}
+
+	from, to := query.Filter.ReleasedFrom, query.Filter.ReleasedTo
+
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return movies.MoviesQuery{}, errors.New("release_date_to is before release_date_from")
+	}
+
+	return query, nil
 }
 
-// Responds with all the movies.
+// Responds with a page of movies matching the query parameters.
 func (mh MoviesHandler) getMovies(w http.ResponseWriter, r *http.Request) {
-	movies, err := mh.db.GetMovies()
+	query, err := moviesQuery(r)
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovies, http.StatusInternalServerError)
+		mh.logger.InfoContext(r.Context(), ErrFailedToGetMovies, "error", err)
+		problem.WriteInvalid(w, ErrFailedToGetMovies, err)
 		return
 	}
 
-	moviesJson, err := json.Marshal(movies)
+	page, err := mh.db.GetMoviesPage(r.Context(), query)
+
+	if err == movies.ErrInvalidCursor {
+		mh.logger.InfoContext(r.Context(), ErrFailedToGetMovies, "error", err)
+		problem.WriteInvalid(w, ErrFailedToGetMovies, err)
+		return
+	}
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovies, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToGetMovies, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetMovies)
+		return
+	}
+
+	pageJson, err := json.Marshal(page)
+
+	if err != nil {
+		mh.logger.ErrorContext(r.Context(), ErrFailedToGetMovies, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetMovies)
+		return
+	}
+
+	setNextPageLink(w, r, page.NextCursor)
+
+	// Removing a movie does not move the newest update, so clients should
+	// prefer the ETag, which follows the content itself.
+	var lastModified time.Time
+
+	for _, movie := range page.Movies {
+		if movie.UpdatedAt.After(lastModified) {
+			lastModified = movie.UpdatedAt
+		}
+	}
+
+	if notModified(w, r, contentETag(pageJson), lastModified) {
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(moviesJson)
+	w.Write(pageJson)
 }
 
-// Responds with details of particular movie.
+// Responds with details of particular movie. Authenticated callers also get
+// the watch status they gave it.
 func (mh MoviesHandler) getMovie(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Query().Get("rating") == "true" {
 		mh.getMovieRating(w, r)
@@ -78,37 +242,52 @@ func (mh MoviesHandler) getMovie(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.GetIDFromPath(r.URL.Path)
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovie, http.StatusBadRequest)
+		mh.logger.InfoContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.WriteInvalid(w, ErrFailedToGetMovie, err)
 		return
 	}
 
-	err = utils.Validate.Var(id, "required,uuid")
+	err = mh.validate.Var(id, "required,uuid")
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovie, http.StatusBadRequest)
+		mh.logger.InfoContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToGetMovie, problem.FieldErrors(err, "id"))
 		return
 	}
 
-	movie, err := mh.db.GetMovie(id)
+	movie, err := mh.db.GetMovie(r.Context(), id)
 
 	if err == movies.ErrNotExists {
-		http.NotFound(w, r)
+		problem.Write(w, http.StatusNotFound, ErrNotExists)
 		return
 	}
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovie, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetMovie)
+		return
+	}
+
+	// The body depends on the caller once their status is in, so shared
+	// caches must keep the responses apart.
+	w.Header().Add("Vary", "Authorization")
+
+	user, authenticated := auth.UserFromContext(r.Context())
+
+	if authenticated {
+		mh.getMovieWithStatus(w, r, movie, user)
 		return
 	}
 
 	movieJson, err := json.Marshal(movie)
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovie, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetMovie)
+		return
+	}
+
+	if notModified(w, r, movieETag(movie.Version), movie.UpdatedAt) {
 		return
 	}
 
@@ -116,92 +295,243 @@ func (mh MoviesHandler) getMovie(w http.ResponseWriter, r *http.Request) {
 	w.Write(movieJson)
 }
 
-// Adds list of movies sent in request.
+// Responds with a movie and the watch status user gave it. The ETag is the
+// version of the movie, so editors can send it back in If-Match. The status
+// changes apart from the movie and the ETag does not cover it, so the
+// response is never 304 and no Last-Modified is sent.
+func (mh MoviesHandler) getMovieWithStatus(w http.ResponseWriter, r *http.Request, movie *models.Movie, user *models.User) {
+	status, err := mh.watchlist.GetStatus(r.Context(), user.ID.String(), movie.ID.String())
+
+	if err != nil && err != watchlist.ErrNotExists {
+		mh.logger.ErrorContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetMovie)
+		return
+	}
+
+	movieJson, err := json.Marshal(models.MovieWithStatus{Movie: *movie, WatchStatus: status})
+
+	if err != nil {
+		mh.logger.ErrorContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetMovie)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private")
+	w.Header().Set("ETag", movieETag(movie.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(movieJson)
+}
+
+// Adds list of movies sent in request. In atomic mode the whole batch fails
+// with the first invalid movie, in partial mode every movie that can be added
+// is, and the others are reported with the reason they failed.
 func (mh MoviesHandler) postMovies(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+
+	if mode == "" {
+		mode = batchModePartial
+	}
+
+	if mode != batchModeAtomic && mode != batchModePartial {
+		problem.WriteFieldErrors(w, ErrInvalidBatchMode, []problem.FieldError{{Field: "mode", Rule: "oneof", Param: "atomic partial", Message: ErrInvalidBatchMode}})
+		return
+	}
+
 	body, err := io.ReadAll(r.Body)
 
 	if err != nil {
-		utils.Logger.Print(err)
-		http.Error(w, ErrFailedToAddMovie, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToAddMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddMovie)
 		return
 	}
 
-	var movies []models.Movie
+	var batch []models.Movie
 
-	if err = json.Unmarshal(body, &movies); err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToAddMovie, http.StatusBadRequest)
+	if err = json.Unmarshal(body, &batch); err != nil {
+		mh.logger.InfoContext(r.Context(), ErrFailedToAddMovie, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddMovie, err)
 		return
 	}
 
-	for _, movie := range movies {
-		if err = utils.Validate.Struct(movie); err != nil {
-			utils.Logger.Println(err)
-			http.Error(w, ErrFailedToAddMovie, http.StatusBadRequest)
+	// Ids are generated by the database unless the client opts in to supply
+	// them, which is meant for migrating data from elsewhere.
+	clientIDs := r.URL.Query().Get("client_ids") == "true"
+
+	var fieldErrors []problem.FieldError
+	var failures []movieFailure
+	var valid []int
+
+	for i, movie := range batch {
+		if movie.ID != uuid.Nil && !clientIDs {
+			mh.logger.InfoContext(r.Context(), ErrClientIDsNotAllowed, "movie_id", movie.ID)
+			problem.Write(w, http.StatusBadRequest, ErrClientIDsNotAllowed)
 			return
 		}
+
+		if err = mh.validate.Struct(movie); err != nil {
+			mh.logger.InfoContext(r.Context(), "invalid movie", "index", i, "error", err)
+			fieldErrors = append(fieldErrors, problem.FieldErrors(err, fmt.Sprintf("[%d]", i))...)
+
+			failures = append(failures, movieFailure{
+				Index:   i,
+				Code:    codeValidation,
+				Message: "movie is invalid",
+				Errors:  problem.FieldErrors(err, ""),
+			})
+			continue
+		}
+
+		valid = append(valid, i)
 	}
 
-	type MovieStatus struct {
-		Status string
-		Movie  models.Movie
+	if mode == batchModeAtomic {
+		if len(fieldErrors) > 0 {
+			problem.WriteFieldErrors(w, ErrFailedToAddMovie, fieldErrors)
+			return
+		}
+
+		ids, err := mh.db.AddMovies(r.Context(), batch)
+
+		if fieldErrors := rowFieldErrors(err); fieldErrors != nil {
+			mh.logger.InfoContext(r.Context(), ErrFailedToAddMovie, "error", err)
+			problem.WriteFieldErrors(w, ErrFailedToAddMovie, fieldErrors)
+			return
+		}
+
+		if err != nil {
+			mh.logger.ErrorContext(r.Context(), ErrFailedToAddMovie, "error", err)
+			problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddMovie)
+			return
+		}
+
+		for i := range batch {
+			batch[i].ID = ids[i]
+		}
+
+		mh.writeAddedMovies(w, r, batch, nil)
+		return
 	}
 
-	status := make(chan MovieStatus)
+	added, addFailures := mh.addMoviesPartially(r.Context(), batch, valid)
 
-	for _, movie := range movies {
-		go func(movie models.Movie) {
-			if err := mh.db.AddMovie(movie); err != nil {
-				utils.Logger.Print(err)
+	failures = append(failures, addFailures...)
 
-				status <- MovieStatus{
-					Status: "failed",
-					Movie:  movie,
-				}
-				return
-			}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Index < failures[j].Index
+	})
 
-			status <- MovieStatus{
-				Status: "success",
-				Movie:  movie,
-			}
-		}(movie)
+	internal := 0
+
+	for _, failure := range failures {
+		if failure.Code == movies.CodeInternal {
+			internal++
+		}
 	}
 
-	type postMoviesResponse struct {
-		AddedMovies  []*models.Movie `json:"added_movies,omitempty"`
-		FailedMovies []*models.Movie `json:"failed_movies,omitempty"`
+	// Nothing was added and it is not the fault of the movies.
+	if len(batch) > 0 && internal == len(batch) {
+		problem.Write(w, problem.FailureStatus(r.Context(), nil), ErrFailedToAddMovie)
+		return
 	}
 
-	response := &postMoviesResponse{}
+	mh.writeAddedMovies(w, r, added, failures)
+}
+
+// Adds the movies at indexes, which passed validation, and returns those that
+// were added along with the failures of the others. A single multi-row insert
+// is tried first, and movies are only inserted one by one when it fails.
+func (mh MoviesHandler) addMoviesPartially(ctx context.Context, batch []models.Movie, indexes []int) ([]models.Movie, []movieFailure) {
+	if len(indexes) == 0 {
+		return nil, nil
+	}
 
-	for range movies {
-		result := <-status
+	candidates := make([]models.Movie, len(indexes))
 
-		switch result.Status {
-		case "failed":
-			response.FailedMovies = append(response.FailedMovies, &result.Movie)
-		case "success":
-			response.AddedMovies = append(response.AddedMovies, &result.Movie)
+	for i, index := range indexes {
+		candidates[i] = batch[index]
+	}
+
+	ids, err := mh.db.AddMovies(ctx, candidates)
+
+	if err == nil {
+		for i := range candidates {
+			candidates[i].ID = ids[i]
 		}
+
+		return candidates, nil
 	}
 
-	responseJSON, err := json.Marshal(response)
+	mh.logger.WarnContext(ctx, "batch insert failed, inserting movies one by one", "error", err)
 
-	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToAddMovie, http.StatusInternalServerError)
-		return
+	errs := make([]error, len(candidates))
+	ids = make([]uuid.UUID, len(candidates))
+
+	var wg sync.WaitGroup
+
+	limit := make(chan struct{}, maxConcurrentInserts)
+
+	for i := range candidates {
+		wg.Add(1)
+		limit <- struct{}{}
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-limit }()
+
+			ids[i], errs[i] = mh.db.AddMovie(ctx, candidates[i])
+		}(i)
 	}
 
-	if len(response.FailedMovies) == len(movies) {
-		http.Error(w, ErrFailedToAddMovie, http.StatusInternalServerError)
+	wg.Wait()
+
+	var added []models.Movie
+	var failures []movieFailure
+
+	for i, err := range errs {
+		if err != nil {
+			mh.logger.InfoContext(ctx, ErrFailedToAddMovie, "index", indexes[i], "error", err)
+
+			code, message := movies.DescribeFailure(err)
+
+			failures = append(failures, movieFailure{Index: indexes[i], Code: code, Message: message})
+			continue
+		}
+
+		candidates[i].ID = ids[i]
+		added = append(added, candidates[i])
+	}
+
+	return added, failures
+}
+
+// Responds with the added movies and the failures of the others, with 207
+// when some movies failed. Location is only set when one movie was added.
+func (mh MoviesHandler) writeAddedMovies(w http.ResponseWriter, r *http.Request, added []models.Movie, failures []movieFailure) {
+	type postMoviesResponse struct {
+		AddedMovies  []models.Movie `json:"added_movies"`
+		FailedMovies []movieFailure `json:"failed_movies,omitempty"`
+	}
+
+	if added == nil {
+		added = []models.Movie{}
+	}
+
+	responseJSON, err := json.Marshal(postMoviesResponse{AddedMovies: added, FailedMovies: failures})
+
+	if err != nil {
+		mh.logger.ErrorContext(r.Context(), ErrFailedToAddMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddMovie)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 
-	if len(response.FailedMovies) > 0 {
+	// Location names a single resource, the ids of more are in the body.
+	if len(added) == 1 {
+		w.Header().Set("Location", "/movies/"+added[0].ID.String())
+	}
+
+	if len(failures) > 0 {
 		w.WriteHeader(http.StatusMultiStatus)
 	} else {
 		w.WriteHeader(http.StatusCreated)
@@ -215,119 +545,104 @@ func (mh MoviesHandler) patchMovie(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.GetIDFromPath(r.URL.Path)
 
 	if err != nil {
-		http.NotFound(w, r)
+		problem.Write(w, http.StatusNotFound, ErrNotExists)
 		return
 	}
 
-	err = utils.Validate.Var(id, "required,uuid")
+	err = mh.validate.Var(id, "required,uuid")
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
+		mh.logger.InfoContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToUpdateMovie, problem.FieldErrors(err, "id"))
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
+	contentType, ok := patchContentType(r)
 
-	if err != nil {
-		utils.Logger.Print(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusInternalServerError)
+	if !ok {
+		w.Header().Set("Accept-Patch", acceptPatch)
+		problem.Write(w, http.StatusUnsupportedMediaType, ErrUnsupportedPatch)
 		return
 	}
 
-	var partialMovie map[string]interface{}
+	body, err := io.ReadAll(r.Body)
 
-	if err = json.Unmarshal(body, &partialMovie); err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
+	if err != nil {
+		mh.logger.ErrorContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateMovie)
 		return
 	}
 
-	existingMovie, err := mh.db.GetMovie(id)
+	existingMovie, err := mh.db.GetMovie(r.Context(), id)
 
 	if err != nil {
 		// If movie does not exist.
 		if err == movies.ErrNotExists {
-			http.NotFound(w, r)
+			problem.Write(w, http.StatusNotFound, ErrNotExists)
+			return
+		}
+
+		mh.logger.ErrorContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateMovie)
+		return
+	}
+
+	if header := r.Header.Get("If-Match"); header != "" && !etagMatches(header, movieETag(existingMovie.Version)) {
+		problem.Write(w, http.StatusPreconditionFailed, ErrPreconditionFailed)
+		return
+	}
+
+	movie, err := applyPatch(*existingMovie, contentType, body)
+
+	if err != nil {
+		mh.logger.InfoContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+
+		// The document is fine, the movie is not in the state it expects.
+		if errors.Is(err, patch.ErrTestFailed) {
+			problem.Write(w, http.StatusConflict, ErrFailedToUpdateMovie+": "+err.Error())
 			return
 		}
 
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusInternalServerError)
-		return
-	}
-
-	// Update fields of existing movie with request.
-	for key, value := range partialMovie {
-		switch key {
-		case "title":
-			if title, ok := value.(string); ok {
-				existingMovie.Title = title
-			} else {
-				utils.Logger.Printf("failed to assert type for field title")
-				http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
-				return
-			}
-		case "release_date":
-			if releaseDate, ok := value.(string); ok {
-				if parsedReleaseDate, err := time.Parse(time.RFC3339, releaseDate); err == nil {
-					existingMovie.ReleaseDate = parsedReleaseDate
-				} else {
-					utils.Logger.Printf("failed to parse release_date: %v", err)
-					http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
-					return
-				}
-			} else {
-				utils.Logger.Printf("failed to assert type for field release_date")
-				http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
-				return
-			}
-		case "genre":
-			if genre, ok := value.(string); ok {
-				existingMovie.Genre = genre
-			} else {
-				utils.Logger.Printf("failed to assert type for field genre")
-				http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
-				return
-			}
-		case "director":
-			if director, ok := value.(string); ok {
-				existingMovie.Director = director
-			} else {
-				utils.Logger.Printf("failed to assert type for field director")
-				http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
-				return
-			}
-		case "description":
-			if description, ok := value.(string); ok {
-				existingMovie.Description = description
-			} else {
-				utils.Logger.Printf("failed to assert type for field description")
-				http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
-				return
-			}
-		}
-	}
-
-	if err := utils.Validate.Struct(existingMovie); err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
-		return
-	}
-
-	err = mh.db.UpdateMovie(id, *existingMovie)
+		problem.WriteInvalid(w, ErrFailedToUpdateMovie, err)
+		return
+	}
+
+	if movie.ID != existingMovie.ID {
+		problem.Write(w, http.StatusBadRequest, ErrMovieIDChanged)
+		return
+	}
+
+	// Timestamps and the version are managed by the server.
+	movie.CreatedAt = existingMovie.CreatedAt
+	movie.UpdatedAt = existingMovie.UpdatedAt
+	movie.Version = existingMovie.Version
+
+	if err := mh.validate.Struct(movie); err != nil {
+		mh.logger.InfoContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+		problem.WriteInvalid(w, ErrFailedToUpdateMovie, err)
+		return
+	}
+
+	// Fails with ErrVersionMismatch if the movie changed since it was read.
+	version, err := mh.db.UpdateMovie(r.Context(), id, movie)
 
 	if err != nil {
 		if err == movies.ErrNotExists {
-			http.NotFound(w, r)
+			problem.Write(w, http.StatusNotFound, ErrNotExists)
+			return
+		}
+
+		if err == movies.ErrVersionMismatch {
+			problem.Write(w, http.StatusPreconditionFailed, ErrPreconditionFailed)
 			return
 		}
 
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateMovie)
 		return
 	}
 
+	w.Header().Set("ETag", movieETag(version))
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -336,28 +651,45 @@ func (mh MoviesHandler) deleteMovie(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.GetIDFromPath(r.URL.Path)
 
 	if err != nil {
-		http.NotFound(w, r)
+		problem.Write(w, http.StatusNotFound, ErrNotExists)
 		return
 	}
 
-	err = utils.Validate.Var(id, "required,uuid")
+	err = mh.validate.Var(id, "required,uuid")
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToDeleteMovie, http.StatusBadRequest)
+		mh.logger.InfoContext(r.Context(), ErrFailedToDeleteMovie, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToDeleteMovie, problem.FieldErrors(err, "id"))
 		return
 	}
 
-	err = mh.db.DeleteMovie(id)
+	version, ok := mh.checkIfMatch(w, r, id, ErrFailedToDeleteMovie)
+
+	if !ok {
+		return
+	}
+
+	err = mh.db.DeleteMovie(r.Context(), id, version)
 
 	if err != nil {
 		if err == movies.ErrNotExists {
-			http.NotFound(w, r)
+			problem.Write(w, http.StatusNotFound, ErrNotExists)
+			return
+		}
+
+		if err == movies.ErrVersionMismatch {
+			problem.Write(w, http.StatusPreconditionFailed, ErrPreconditionFailed)
 			return
 		}
 
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToDeleteMovie, http.StatusInternalServerError)
+		if errors.Is(err, movies.ErrMovieInUse) {
+			mh.logger.InfoContext(r.Context(), ErrFailedToDeleteMovie, "error", err)
+			problem.Write(w, http.StatusConflict, ErrFailedToDeleteMovie+": "+err.Error())
+			return
+		}
+
+		mh.logger.ErrorContext(r.Context(), ErrFailedToDeleteMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToDeleteMovie)
 		return
 	}
 
@@ -369,52 +701,75 @@ func (mh MoviesHandler) putMovie(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.GetIDFromPath(r.URL.Path)
 
 	if err != nil {
-		http.NotFound(w, r)
+		problem.Write(w, http.StatusNotFound, ErrNotExists)
 		return
 	}
 
-	err = utils.Validate.Var(id, "required,uuid")
+	err = mh.validate.Var(id, "required,uuid")
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
+		mh.logger.InfoContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToUpdateMovie, problem.FieldErrors(err, "id"))
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 
 	if err != nil {
-		utils.Logger.Print(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateMovie)
+		return
 	}
 
 	var movie models.Movie
 
 	if err = json.Unmarshal(body, &movie); err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
+		mh.logger.InfoContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+		problem.WriteInvalid(w, ErrFailedToUpdateMovie, err)
+		return
+	}
+
+	// Movie keeps its id when the body leaves it out, and can never change it.
+	if movie.ID == uuid.Nil {
+		movie.ID = uuid.MustParse(id)
+	}
+
+	if movie.ID != uuid.MustParse(id) {
+		problem.Write(w, http.StatusBadRequest, ErrMovieIDChanged)
 		return
 	}
 
-	if err = utils.Validate.Struct(movie); err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusBadRequest)
+	if err = mh.validate.Struct(movie); err != nil {
+		mh.logger.InfoContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+		problem.WriteInvalid(w, ErrFailedToUpdateMovie, err)
 		return
 	}
 
-	err = mh.db.UpdateMovie(id, movie)
+	var ok bool
+
+	if movie.Version, ok = mh.checkIfMatch(w, r, id, ErrFailedToUpdateMovie); !ok {
+		return
+	}
+
+	version, err := mh.db.UpdateMovie(r.Context(), id, movie)
 
 	if err != nil {
 		if err == movies.ErrNotExists {
-			http.NotFound(w, r)
+			problem.Write(w, http.StatusNotFound, ErrNotExists)
 			return
 		}
 
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToUpdateMovie, http.StatusInternalServerError)
+		if err == movies.ErrVersionMismatch {
+			problem.Write(w, http.StatusPreconditionFailed, ErrPreconditionFailed)
+			return
+		}
+
+		mh.logger.ErrorContext(r.Context(), ErrFailedToUpdateMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateMovie)
 		return
 	}
 
+	w.Header().Set("ETag", movieETag(version))
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -423,90 +778,136 @@ func (mh MoviesHandler) putMovies(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 
 	if err != nil {
-		utils.Logger.Print(err)
-		http.Error(w, ErrFailedToReplaceMovies, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToReplaceMovies, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToReplaceMovies)
 		return
 	}
 
-	var movies []*models.Movie
+	var catalog []*models.Movie
 
-	if err := json.Unmarshal(body, &movies); err != nil {
-		utils.Logger.Print(err)
-		http.Error(w, ErrFailedToReplaceMovies, http.StatusBadRequest)
+	if err := json.Unmarshal(body, &catalog); err != nil {
+		mh.logger.InfoContext(r.Context(), ErrFailedToReplaceMovies, "error", err)
+		problem.WriteInvalid(w, ErrFailedToReplaceMovies, err)
 		return
 	}
 
-	for _, movie := range movies {
-		if err := utils.Validate.Struct(movie); err != nil {
-			utils.Logger.Print(err)
-			http.Error(w, ErrFailedToReplaceMovies, http.StatusBadRequest)
-			return
+	var fieldErrors []problem.FieldError
+
+	for i, movie := range catalog {
+		if err := mh.validate.Struct(movie); err != nil {
+			mh.logger.InfoContext(r.Context(), "invalid movie", "index", i, "error", err)
+			fieldErrors = append(fieldErrors, problem.FieldErrors(err, fmt.Sprintf("[%d]", i))...)
+			continue
 		}
+
+		if movie.ID == uuid.Nil {
+			movie.ID = uuid.New()
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		problem.WriteFieldErrors(w, ErrFailedToReplaceMovies, fieldErrors)
+		return
+	}
+
+	err = mh.db.ReplaceMovies(r.Context(), catalog)
+
+	if errors.Is(err, movies.ErrMovieInUse) {
+		mh.logger.InfoContext(r.Context(), ErrFailedToReplaceMovies, "error", err)
+		problem.Write(w, http.StatusConflict, ErrFailedToReplaceMovies+": "+err.Error())
+		return
 	}
 
-	err = mh.db.ReplaceMovies(movies)
+	if fieldErrors := rowFieldErrors(err); fieldErrors != nil {
+		mh.logger.InfoContext(r.Context(), ErrFailedToReplaceMovies, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToReplaceMovies, fieldErrors)
+		return
+	}
 
 	if err != nil {
-		utils.Logger.Print(err)
-		http.Error(w, ErrFailedToReplaceMovies, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToReplaceMovies, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToReplaceMovies)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Returns field errors for a movie of a batch the database rejected, nil
+// when err is not about a single movie. They have the code and message of
+// the failures of partial batches, the database error is only logged.
+func rowFieldErrors(err error) []problem.FieldError {
+	var rowErr *movies.RowError
+
+	if !errors.As(err, &rowErr) {
+		return nil
+	}
+
+	prefix := fmt.Sprintf("[%d]", rowErr.Index)
+
+	field := prefix
+
+	if rowErr.Field != "" {
+		field = prefix + "." + rowErr.Field
+	}
+
+	code, message := movies.DescribeFailure(rowErr)
+
+	return []problem.FieldError{{Field: field, Rule: code, Message: message}}
+}
+
 // Responds with movie details along with its rating.
 func (mh MoviesHandler) getMovieRating(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.GetIDFromPath(r.URL.Path)
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovie, http.StatusBadRequest)
+		mh.logger.InfoContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.WriteInvalid(w, ErrFailedToGetMovie, err)
 		return
 	}
 
-	err = utils.Validate.Var(id, "required,uuid")
+	err = mh.validate.Var(id, "required,uuid")
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovie, http.StatusBadRequest)
+		mh.logger.InfoContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToGetMovie, problem.FieldErrors(err, "id"))
 		return
 	}
 
-	_, err = mh.db.GetMovie(id)
+	_, err = mh.db.GetMovie(r.Context(), id)
 
 	if err == movies.ErrNotExists {
-		http.NotFound(w, r)
+		problem.Write(w, http.StatusNotFound, ErrNotExists)
 		return
 	}
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovie, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetMovie)
 		return
 	}
 
-	review, err := mh.db.GetMovieRating(id)
+	review, err := mh.db.GetMovieRating(r.Context(), id)
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovie, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetMovie)
 		return
 	}
 
-	err = utils.Validate.Struct(review)
+	err = mh.validate.Struct(review)
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovie, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetMovie)
 		return
 	}
 
 	reviewJson, err := json.Marshal(review)
 
 	if err != nil {
-		utils.Logger.Println(err)
-		http.Error(w, ErrFailedToGetMovie, http.StatusInternalServerError)
+		mh.logger.ErrorContext(r.Context(), ErrFailedToGetMovie, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetMovie)
 		return
 	}
 
@@ -514,11 +915,119 @@ func (mh MoviesHandler) getMovieRating(w http.ResponseWriter, r *http.Request) {
 	w.Write(reviewJson)
 }
 
+// Responds with movies matching the q query parameter, most relevant first.
+func (mh MoviesHandler) searchMovies(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	if q == "" || len(q) > maxSearchQueryLength {
+		mh.logger.InfoContext(r.Context(), "invalid search query", "length", len(q))
+		problem.Write(w, http.StatusBadRequest, ErrFailedToSearchMovies)
+		return
+	}
+
+	limit, err := pageLimit(r)
+
+	if err != nil {
+		mh.logger.InfoContext(r.Context(), ErrFailedToSearchMovies, "error", err)
+		problem.WriteInvalid(w, ErrFailedToSearchMovies, err)
+		return
+	}
+
+	results, err := mh.db.SearchMovies(r.Context(), q, limit)
+
+	if err != nil {
+		mh.logger.ErrorContext(r.Context(), ErrFailedToSearchMovies, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToSearchMovies)
+		return
+	}
+
+	type searchMoviesResponse struct {
+		Results []*models.MovieSearchResult `json:"results"`
+	}
+
+	resultsJson, err := json.Marshal(searchMoviesResponse{Results: results})
+
+	if err != nil {
+		mh.logger.ErrorContext(r.Context(), ErrFailedToSearchMovies, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToSearchMovies)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resultsJson)
+}
+
+// Responds with a short list of titles for the prefix query parameter,
+// tolerating misspellings.
+func (mh MoviesHandler) autocompleteMovies(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimSpace(r.URL.Query().Get("prefix"))
+
+	if prefix == "" || len(prefix) > maxSearchQueryLength {
+		mh.logger.InfoContext(r.Context(), "invalid autocomplete prefix", "length", len(prefix))
+		problem.Write(w, http.StatusBadRequest, ErrFailedToAutocomplete)
+		return
+	}
+
+	limit := defaultSuggestionLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxSuggestionLimit {
+			mh.logger.InfoContext(r.Context(), "invalid autocomplete limit", "limit", value)
+			problem.Write(w, http.StatusBadRequest, ErrFailedToAutocomplete)
+			return
+		}
+	}
+
+	suggestions, err := mh.db.AutocompleteMovies(r.Context(), prefix, limit)
+
+	if err != nil {
+		mh.logger.ErrorContext(r.Context(), ErrFailedToAutocomplete, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAutocomplete)
+		return
+	}
+
+	suggestionsJson, err := json.Marshal(suggestions)
+
+	if err != nil {
+		mh.logger.ErrorContext(r.Context(), ErrFailedToAutocomplete, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAutocomplete)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(suggestionsJson)
+}
+
+// Methods the movies routes allow, advertised by Options and 405 responses.
+const moviesMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+
+// Methods the read only routes under /movies/ allow.
+const readOnlyMethods = "GET, OPTIONS"
+
+// Serves a read only route, answering other methods with 405.
+func (mh MoviesHandler) serveReadOnly(w http.ResponseWriter, r *http.Request, get http.HandlerFunc) {
+	switch r.Method {
+	case http.MethodGet:
+		get(w, r)
+	case http.MethodOptions:
+		w.Header().Set("Access-Control-Allow-Methods", readOnlyMethods)
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMethodNotAllowed(w, r, readOnlyMethods)
+	}
+}
+
 // Responds with allowed methods.
 func (mh MoviesHandler) Options(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", moviesMethods)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, If-Modified-Since")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Accept-Patch", acceptPatch)
 	w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -526,6 +1035,29 @@ func (mh MoviesHandler) Options(w http.ResponseWriter, r *http.Request) {
 func (mh MoviesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	isCollectionPath := r.URL.Path == "/movies" || r.URL.Path == "/movies/"
 
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/movies/search":
+		mh.serveReadOnly(w, r, mh.searchMovies)
+		return
+	case "/movies/autocomplete":
+		mh.serveReadOnly(w, r, mh.autocompleteMovies)
+		return
+	}
+
+	// Reading the catalog is public, changing it needs a role allowed to.
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		perm := auth.PermWriteMovies
+
+		if isCollectionPath && r.Method == http.MethodPut {
+			perm = auth.PermReplaceMovies
+		}
+
+		if _, ok := authorize(w, r, mh.policy, perm); !ok {
+			return
+		}
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		if isCollectionPath {
@@ -537,7 +1069,7 @@ func (mh MoviesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if isCollectionPath {
 			mh.postMovies(w, r)
 		} else {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w, r, moviesMethods)
 		}
 	case http.MethodPut:
 		if isCollectionPath {
@@ -547,19 +1079,19 @@ func (mh MoviesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	case http.MethodPatch:
 		if isCollectionPath {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w, r, moviesMethods)
 		} else {
 			mh.patchMovie(w, r)
 		}
 	case http.MethodDelete:
 		if isCollectionPath {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w, r, moviesMethods)
 		} else {
 			mh.deleteMovie(w, r)
 		}
 	case http.MethodOptions:
 		mh.Options(w, r)
 	default:
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r, moviesMethods)
 	}
 }
@@ -2,25 +2,35 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"moviepin/auth"
 	"moviepin/db/movies"
 	"moviepin/mocks"
 	"moviepin/models"
+	"moviepin/problem"
+	"moviepin/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 func TestGetMovies(t *testing.T) {
 	t.Run("get movies", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
-		repo.GetMoviesError = nil
+		repo.GetMoviesPageError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies", nil)
 		if err != nil {
@@ -38,24 +48,103 @@ func TestGetMovies(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		var movies []*models.Movie
+		var page models.MoviesPage
+
+		if err := json.Unmarshal(body, &page); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(page.Movies) != 1 {
+			t.Fatalf("wrong number of movies, got %v want %v", len(page.Movies), 1)
+		}
+
+		assertMovie(t, page.Movies[0], &mocks.Movie)
+
+		if page.NextCursor != "" {
+			t.Errorf("unexpected next cursor %v on last page", page.NextCursor)
+		}
+	})
+
+	t.Run("get movies invalid limit", func(t *testing.T) {
+		for _, limit := range []string{"0", "-1", "101", "ten"} {
+			handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest("GET", "/movies?limit="+limit, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.getMovies(rr, req)
+
+			assertStatusCode(t, rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("get movies filtered and sorted", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
-		if err := json.Unmarshal(body, &movies); err != nil {
+		req, err := http.NewRequest("GET", "/movies?genre=Drama&director=Frank+Darabont&release_date_from=1990-01-01&release_date_to=2000-12-31&title_prefix=The&sort=-release_date,title", nil)
+		if err != nil {
 			t.Fatal(err)
 		}
 
-		if len(movies) != 1 {
-			t.Errorf("wrong number of movies, got %v want %v", len(movies), 1)
+		rr := httptest.NewRecorder()
+
+		handler.getMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+	})
+
+	t.Run("get movies invalid query", func(t *testing.T) {
+		for _, query := range []string{
+			"sort=description",
+			"sort=title,title",
+			"gnere=Drama",
+			"release_date_from=1990",
+			"release_date_from=2000-01-01&release_date_to=1990-01-01",
+		} {
+			handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest("GET", "/movies?"+query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.getMovies(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: wrong status code, got %d want %d", query, rr.Code, http.StatusBadRequest)
+			}
+		}
+	})
+
+	t.Run("get movies invalid cursor", func(t *testing.T) {
+		repo := mocks.NewMoviesRepository()
+		repo.GetMoviesPageError = movies.ErrInvalidCursor
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", "/movies?cursor=garbage", nil)
+		if err != nil {
+			t.Fatal(err)
 		}
 
-		assertMovie(t, movies[0], &mocks.Movie)
+		rr := httptest.NewRecorder()
+
+		handler.getMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
 	})
 
 	t.Run("get movies error", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
-		repo.GetMoviesError = errors.New("error")
+		repo.GetMoviesPageError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies", nil)
 		if err != nil {
@@ -75,7 +164,7 @@ func TestGetMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.GetMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
 		if err != nil {
@@ -106,7 +195,7 @@ func TestGetMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.GetMovieError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/1", nil)
 		if err != nil {
@@ -124,7 +213,7 @@ func TestGetMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.GetMovieError = movies.ErrNotExists
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
 		if err != nil {
@@ -136,13 +225,17 @@ func TestGetMovie(t *testing.T) {
 		handler.getMovie(rr, req)
 
 		assertStatusCode(t, rr.Code, http.StatusNotFound)
+
+		if details := assertProblem(t, rr); details.Detail != ErrNotExists {
+			t.Errorf("wrong detail, got %v want %v", details.Detail, ErrNotExists)
+		}
 	})
 
 	t.Run("get movie error", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.GetMovieError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
 		if err != nil {
@@ -155,6 +248,24 @@ func TestGetMovie(t *testing.T) {
 
 		assertStatusCode(t, rr.Code, http.StatusInternalServerError)
 	})
+
+	t.Run("get movie timeout", func(t *testing.T) {
+		repo := mocks.NewMoviesRepository()
+		repo.GetMovieError = context.DeadlineExceeded
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getMovie(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusGatewayTimeout)
+	})
 }
 
 func TestGetMovieRating(t *testing.T) {
@@ -163,7 +274,7 @@ func TestGetMovieRating(t *testing.T) {
 		repo.GetMovieError = nil
 		repo.GetMovieRatingError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/550e8400-e29b-41d4-a716-446655440000?rating=true", nil)
 		if err != nil {
@@ -195,7 +306,7 @@ func TestGetMovieRating(t *testing.T) {
 	t.Run("get movie rating wrong path", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/1?rating=true", nil)
 		if err != nil {
@@ -213,7 +324,7 @@ func TestGetMovieRating(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.GetMovieError = movies.ErrNotExists
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/550e8400-e29b-41d4-a716-446655440000?rating=true", nil)
 		if err != nil {
@@ -231,7 +342,7 @@ func TestGetMovieRating(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.GetMovieError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/550e8400-e29b-41d4-a716-446655440000?rating=true", nil)
 		if err != nil {
@@ -249,7 +360,7 @@ func TestGetMovieRating(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.GetMovieRatingError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/550e8400-e29b-41d4-a716-446655440000?rating=true", nil)
 		if err != nil {
@@ -264,12 +375,196 @@ func TestGetMovieRating(t *testing.T) {
 	})
 }
 
+func TestSearchMovies(t *testing.T) {
+	t.Run("search movies", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", "/movies/search?q=shawshank", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.searchMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+
+		var response struct {
+			Results []*models.MovieSearchResult `json:"results"`
+		}
+
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.Results) != 1 {
+			t.Fatalf("wrong number of results, got %v want %v", len(response.Results), 1)
+		}
+
+		if !reflect.DeepEqual(response.Results[0], &mocks.MovieSearchResult) {
+			t.Errorf("wrong result, got %v want %v", response.Results[0], &mocks.MovieSearchResult)
+		}
+	})
+
+	t.Run("search movies without query", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", "/movies/search?q=+", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.searchMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+	})
+
+	t.Run("search movies error", func(t *testing.T) {
+		repo := mocks.NewMoviesRepository()
+		repo.SearchMoviesError = errors.New("error")
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", "/movies/search?q=shawshank", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.searchMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusInternalServerError)
+	})
+}
+
+func TestAutocompleteMovies(t *testing.T) {
+	t.Run("autocomplete movies", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", "/movies/autocomplete?prefix=shawshenk", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.autocompleteMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+
+		var suggestions []*models.MovieSuggestion
+
+		if err := json.Unmarshal(rr.Body.Bytes(), &suggestions); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(suggestions) != 1 || !reflect.DeepEqual(suggestions[0], &mocks.MovieSuggestion) {
+			t.Errorf("wrong suggestions, got %v want %v", suggestions, []*models.MovieSuggestion{&mocks.MovieSuggestion})
+		}
+	})
+
+	t.Run("autocomplete movies invalid request", func(t *testing.T) {
+		for _, query := range []string{"", "prefix=", "prefix=shaw&limit=21", "prefix=shaw&limit=0"} {
+			handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest("GET", "/movies/autocomplete?"+query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.autocompleteMovies(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%q: wrong status code, got %d want %d", query, rr.Code, http.StatusBadRequest)
+			}
+		}
+	})
+
+	t.Run("autocomplete movies error", func(t *testing.T) {
+		repo := mocks.NewMoviesRepository()
+		repo.AutocompleteMoviesError = errors.New("error")
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", "/movies/autocomplete?prefix=shaw", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.autocompleteMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusInternalServerError)
+	})
+}
+
 func TestPostMovies(t *testing.T) {
 	t.Run("post movie", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.AddMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := moviesRequestBody(t, []*models.Movie{newMovie()})
+
+		req, err := http.NewRequest("POST", "/movies", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusCreated)
+
+		var response struct {
+			AddedMovies []*models.Movie `json:"added_movies"`
+		}
+
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.AddedMovies) != 1 || response.AddedMovies[0].ID == uuid.Nil {
+			t.Fatalf("wrong added movies, got %v", response.AddedMovies)
+		}
+
+		if got, want := rr.Header().Get("Location"), "/movies/"+response.AddedMovies[0].ID.String(); got != want {
+			t.Errorf("wrong Location, got %v want %v", got, want)
+		}
+	})
+
+	t.Run("post movies without location", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := moviesRequestBody(t, []*models.Movie{newMovie(), newMovie()})
+
+		req, err := http.NewRequest("POST", "/movies", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusCreated)
+
+		if got := rr.Header().Values("Location"); len(got) != 0 {
+			t.Errorf("unexpected Location %v", got)
+		}
+	})
+
+	t.Run("post movie with id", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie})
 
@@ -282,16 +577,207 @@ func TestPostMovies(t *testing.T) {
 
 		handler.postMovies(rr, req)
 
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+	})
+
+	t.Run("post movie with client ids", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie})
+
+		req, err := http.NewRequest("POST", "/movies?client_ids=true", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postMovies(rr, req)
+
 		assertStatusCode(t, rr.Code, http.StatusCreated)
+
+		if got, want := rr.Header().Get("Location"), "/movies/"+mocks.Movie.ID.String(); got != want {
+			t.Errorf("wrong Location, got %v want %v", got, want)
+		}
+	})
+
+	t.Run("post movies atomic invalid", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		invalid := newMovie()
+		invalid.Title = ""
+
+		body := moviesRequestBody(t, []*models.Movie{newMovie(), invalid})
+
+		req, err := http.NewRequest("POST", "/movies?mode=atomic", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+
+		details := assertProblem(t, rr)
+
+		if len(details.Errors) != 1 {
+			t.Fatalf("wrong number of field errors, got %v want %v", len(details.Errors), 1)
+		}
+
+		if details.Errors[0].Field != "[1].title" || details.Errors[0].Rule != "required" {
+			t.Errorf("wrong field error, got %+v", details.Errors[0])
+		}
+	})
+
+	t.Run("post movies partial invalid", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		invalid := newMovie()
+		invalid.Title = ""
+
+		body := moviesRequestBody(t, []*models.Movie{newMovie(), invalid})
+
+		req, err := http.NewRequest("POST", "/movies?mode=partial", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusMultiStatus)
+
+		var response struct {
+			AddedMovies  []*models.Movie `json:"added_movies"`
+			FailedMovies []movieFailure  `json:"failed_movies"`
+		}
+
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.AddedMovies) != 1 {
+			t.Errorf("wrong number of added movies, got %v want %v", len(response.AddedMovies), 1)
+		}
+
+		if len(response.FailedMovies) != 1 {
+			t.Fatalf("wrong number of failed movies, got %v want %v", len(response.FailedMovies), 1)
+		}
+
+		failure := response.FailedMovies[0]
+
+		if failure.Index != 1 || failure.Code != codeValidation || len(failure.Errors) != 1 || failure.Errors[0].Field != "title" {
+			t.Errorf("wrong failure, got %+v", failure)
+		}
+	})
+
+	t.Run("post movies partial falls back to single inserts", func(t *testing.T) {
+		repo := mocks.NewMoviesRepository()
+		repo.AddMoviesError = &movies.RowError{Index: 0, Code: "unique_violation", Err: movies.ErrDuplicateID}
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := moviesRequestBody(t, []*models.Movie{newMovie(), newMovie()})
+
+		req, err := http.NewRequest("POST", "/movies", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postMovies(rr, req)
+
+		// The mock only fails the batch insert, so single inserts succeed.
+		assertStatusCode(t, rr.Code, http.StatusCreated)
+	})
+
+	t.Run("post movies atomic rejected", func(t *testing.T) {
+		repo := mocks.NewMoviesRepository()
+		repo.AddMoviesError = &movies.RowError{Index: 1, Field: "id", Code: "unique_violation", Err: movies.ErrDuplicateID}
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := moviesRequestBody(t, []*models.Movie{newMovie(), newMovie()})
+
+		req, err := http.NewRequest("POST", "/movies?mode=atomic", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+
+		details := assertProblem(t, rr)
+
+		if len(details.Errors) != 1 || details.Errors[0].Field != "[1].id" {
+			t.Errorf("wrong field errors, got %+v", details.Errors)
+		}
+	})
+
+	t.Run("post movies atomic hides database error", func(t *testing.T) {
+		pqErr := &pq.Error{Code: "23505", Constraint: "movies_pkey", Message: `duplicate key value violates unique constraint "movies_pkey"`}
+
+		repo := mocks.NewMoviesRepository()
+		repo.AddMoviesError = &movies.RowError{Index: 0, Field: "id", Code: "unique_violation", Err: pqErr}
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := moviesRequestBody(t, []*models.Movie{newMovie()})
+
+		req, err := http.NewRequest("POST", "/movies?mode=atomic", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+
+		if strings.Contains(rr.Body.String(), "movies_pkey") {
+			t.Errorf("response leaks database error %s", rr.Body.String())
+		}
+
+		details := assertProblem(t, rr)
+
+		if len(details.Errors) != 1 || details.Errors[0].Rule != movies.CodeDuplicateKey {
+			t.Errorf("wrong field errors, got %+v", details.Errors)
+		}
+	})
+
+	t.Run("post movies invalid mode", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := moviesRequestBody(t, []*models.Movie{newMovie()})
+
+		req, err := http.NewRequest("POST", "/movies?mode=best_effort", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
 	})
 
 	t.Run("post movie error all fail", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
+		repo.AddMoviesError = errors.New("error")
 		repo.AddMovieError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
-		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie})
+		body := moviesRequestBody(t, []*models.Movie{newMovie()})
 
 		req, err := http.NewRequest("POST", "/movies", body)
 		if err != nil {
@@ -311,7 +797,7 @@ func TestDeleteMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.DeleteMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("DELETE", "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
 		if err != nil {
@@ -329,7 +815,7 @@ func TestDeleteMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.DeleteMovieError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("DELETE", "/movies/1", nil)
 		if err != nil {
@@ -347,7 +833,7 @@ func TestDeleteMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.DeleteMovieError = movies.ErrNotExists
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("DELETE", "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
 		if err != nil {
@@ -365,7 +851,7 @@ func TestDeleteMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.DeleteMovieError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("DELETE", "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
 		if err != nil {
@@ -378,6 +864,24 @@ func TestDeleteMovie(t *testing.T) {
 
 		assertStatusCode(t, rr.Code, http.StatusInternalServerError)
 	})
+
+	t.Run("delete movie in use", func(t *testing.T) {
+		repo := mocks.NewMoviesRepository()
+		repo.DeleteMovieError = fmt.Errorf("%w: %s", movies.ErrMovieInUse, "550e8400-e29b-41d4-a716-446655440000")
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("DELETE", "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.deleteMovie(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusConflict)
+	})
 }
 
 func TestPutMovie(t *testing.T) {
@@ -385,10 +889,48 @@ func TestPutMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.UpdateMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := movieRequestBody(t, &mocks.Movie)
+
+		req, err := http.NewRequest("PUT", "/movies/"+mocks.Movie.ID.String(), body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.putMovie(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusNoContent)
+	})
+
+	t.Run("put movie changing id", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
+		req, err := http.NewRequest("PUT", "/movies/550e8400-e29b-41d4-a716-446655440001", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.putMovie(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+
+		if details := assertProblem(t, rr); details.Detail != ErrMovieIDChanged {
+			t.Errorf("wrong detail, got %v want %v", details.Detail, ErrMovieIDChanged)
+		}
+	})
+
+	t.Run("put movie without id", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := movieRequestBody(t, newMovie())
+
 		req, err := http.NewRequest("PUT", "/movies/550e8400-e29b-41d4-a716-446655440000", body)
 		if err != nil {
 			t.Fatal(err)
@@ -404,7 +946,7 @@ func TestPutMovie(t *testing.T) {
 	t.Run("put movie wrong path", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
@@ -424,11 +966,11 @@ func TestPutMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.UpdateMovieError = movies.ErrNotExists
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
-		req, err := http.NewRequest("PUT", "/movies/550e8400-e29b-41d4-a716-446655440000", body)
+		req, err := http.NewRequest("PUT", "/movies/"+mocks.Movie.ID.String(), body)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -444,11 +986,11 @@ func TestPutMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.UpdateMovieError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
-		req, err := http.NewRequest("PUT", "/movies/550e8400-e29b-41d4-a716-446655440000", body)
+		req, err := http.NewRequest("PUT", "/movies/"+mocks.Movie.ID.String(), body)
 		if err != nil {
 			t.Fatal(err)
 
@@ -467,7 +1009,7 @@ func TestPutMovies(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.ReplaceMoviesError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie})
 
@@ -487,7 +1029,7 @@ func TestPutMovies(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.ReplaceMoviesError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie})
 
@@ -502,6 +1044,75 @@ func TestPutMovies(t *testing.T) {
 
 		assertStatusCode(t, rr.Code, http.StatusInternalServerError)
 	})
+
+	t.Run("put movies timed out", func(t *testing.T) {
+		repo := mocks.NewMoviesRepository()
+		repo.ReplaceMoviesError = &pq.Error{Code: "57014"}
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie})
+
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, "PUT", "/movies", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.putMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusGatewayTimeout)
+	})
+
+	t.Run("put movies leaving out movie in use", func(t *testing.T) {
+		repo := mocks.NewMoviesRepository()
+		repo.ReplaceMoviesError = fmt.Errorf("%w: %s", movies.ErrMovieInUse, uuid.New())
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie})
+
+		req, err := http.NewRequest("PUT", "/movies", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.putMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusConflict)
+	})
+
+	t.Run("put movies row rejected", func(t *testing.T) {
+		repo := mocks.NewMoviesRepository()
+		repo.ReplaceMoviesError = &movies.RowError{Index: 1, Field: "id", Code: "unique_violation", Err: movies.ErrDuplicateID}
+
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie, &mocks.Movie})
+
+		req, err := http.NewRequest("PUT", "/movies", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.putMovies(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+
+		details := assertProblem(t, rr)
+
+		if len(details.Errors) != 1 || details.Errors[0].Field != "[1].id" || details.Errors[0].Rule != movies.CodeDuplicateKey {
+			t.Errorf("wrong field errors, got %+v", details.Errors)
+		}
+	})
 }
 
 func TestPatchMovie(t *testing.T) {
@@ -509,7 +1120,7 @@ func TestPatchMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.UpdateMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
@@ -529,7 +1140,7 @@ func TestPatchMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.UpdateMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		movie := make(map[string]interface{})
 		movie["title"] = "updated title"
@@ -556,7 +1167,7 @@ func TestPatchMovie(t *testing.T) {
 	t.Run("patch movie wrong path", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
@@ -576,7 +1187,7 @@ func TestPatchMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.UpdateMovieError = movies.ErrNotExists
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
@@ -596,7 +1207,7 @@ func TestPatchMovie(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.UpdateMovieError = errors.New("error")
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
@@ -617,7 +1228,7 @@ func TestOptions(t *testing.T) {
 	t.Run("options", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("OPTIONS", "/movies", nil)
 		if err != nil {
@@ -632,8 +1243,8 @@ func TestOptions(t *testing.T) {
 			t.Errorf("wrong Access-Control-Allow-Methods, got %v want %v", rr.Header().Get("Access-Control-Allow-Methods"), "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		}
 
-		if got, want := rr.Header().Get("Access-Control-Allow-Headers"), "Content-Type"; got != want {
-			t.Errorf("wrong Access-Control-Allow-Headers, got %v want %v", rr.Header().Get("Access-Control-Allow-Headers"), "Content-Type")
+		if got, want := rr.Header().Get("Access-Control-Allow-Headers"), "Content-Type, Authorization, If-Match, If-None-Match, If-Modified-Since"; got != want {
+			t.Errorf("wrong Access-Control-Allow-Headers, got %v want %v", got, want)
 		}
 
 		if got, want := rr.Header().Get("Access-Control-Allow-Origin"), "*"; got != want {
@@ -653,7 +1264,7 @@ func TestServeHTTP(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.GetMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
 		if err != nil {
@@ -669,9 +1280,9 @@ func TestServeHTTP(t *testing.T) {
 
 	t.Run("get movies path", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
-		repo.GetMoviesError = nil
+		repo.GetMoviesPageError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies", nil)
 		if err != nil {
@@ -685,11 +1296,59 @@ func TestServeHTTP(t *testing.T) {
 		assertStatusCode(t, rr.Code, http.StatusOK)
 	})
 
+	t.Run("search movies path", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", "/movies/search?q=prison", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+	})
+
+	t.Run("autocomplete movies path", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", "/movies/autocomplete?prefix=shaw", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+	})
+
+	t.Run("post search path", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("POST", "/movies/search", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.Admin)
+
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusMethodNotAllowed)
+		assertAllow(t, rr, readOnlyMethods)
+	})
+
 	t.Run("get movie rating path", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.GetMovieRatingError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("GET", "/movies/550e8400-e29b-41d4-a716-446655440000?rating=true", nil)
 		if err != nil {
@@ -707,15 +1366,17 @@ func TestServeHTTP(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.AddMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
-		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie})
+		body := moviesRequestBody(t, []*models.Movie{newMovie()})
 
 		req, err := http.NewRequest("POST", "/movies", body)
 		if err != nil {
 			t.Fatal(err)
 		}
 
+		req = withUser(req, &mocks.Admin)
+
 		rr := httptest.NewRecorder()
 
 		handler.ServeHTTP(rr, req)
@@ -726,7 +1387,7 @@ func TestServeHTTP(t *testing.T) {
 	t.Run("post movie path", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie})
 
@@ -735,18 +1396,21 @@ func TestServeHTTP(t *testing.T) {
 			t.Fatal(err)
 		}
 
+		req = withUser(req, &mocks.Admin)
+
 		rr := httptest.NewRecorder()
 
 		handler.ServeHTTP(rr, req)
 
 		assertStatusCode(t, rr.Code, http.StatusMethodNotAllowed)
+		assertAllow(t, rr, moviesMethods)
 	})
 
 	t.Run("patch movie path", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.UpdateMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
@@ -755,6 +1419,8 @@ func TestServeHTTP(t *testing.T) {
 			t.Fatal(err)
 		}
 
+		req = withUser(req, &mocks.Admin)
+
 		rr := httptest.NewRecorder()
 
 		handler.ServeHTTP(rr, req)
@@ -766,7 +1432,7 @@ func TestServeHTTP(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.UpdateMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
@@ -775,26 +1441,31 @@ func TestServeHTTP(t *testing.T) {
 			t.Fatal(err)
 		}
 
+		req = withUser(req, &mocks.Admin)
+
 		rr := httptest.NewRecorder()
 
 		handler.ServeHTTP(rr, req)
 
 		assertStatusCode(t, rr.Code, http.StatusMethodNotAllowed)
+		assertAllow(t, rr, moviesMethods)
 	})
 
 	t.Run("put movie path", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.UpdateMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := movieRequestBody(t, &mocks.Movie)
 
-		req, err := http.NewRequest("PUT", "/movies/550e8400-e29b-41d4-a716-446655440000", body)
+		req, err := http.NewRequest("PUT", "/movies/"+mocks.Movie.ID.String(), body)
 		if err != nil {
 			t.Fatal(err)
 		}
 
+		req = withUser(req, &mocks.Admin)
+
 		rr := httptest.NewRecorder()
 
 		handler.ServeHTTP(rr, req)
@@ -806,7 +1477,7 @@ func TestServeHTTP(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.ReplaceMoviesError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		body := moviesRequestBody(t, []*models.Movie{&mocks.Movie})
 
@@ -815,6 +1486,8 @@ func TestServeHTTP(t *testing.T) {
 			t.Fatal(err)
 		}
 
+		req = withUser(req, &mocks.Admin)
+
 		rr := httptest.NewRecorder()
 
 		handler.ServeHTTP(rr, req)
@@ -827,13 +1500,15 @@ func TestServeHTTP(t *testing.T) {
 		repo.GetMovieError = nil
 		repo.DeleteMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("DELETE", "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
 
+		req = withUser(req, &mocks.Admin)
+
 		rr := httptest.NewRecorder()
 
 		handler.ServeHTTP(rr, req)
@@ -845,24 +1520,27 @@ func TestServeHTTP(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 		repo.DeleteMovieError = nil
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest(http.MethodDelete, "/movies", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
 
+		req = withUser(req, &mocks.Admin)
+
 		rr := httptest.NewRecorder()
 
 		handler.ServeHTTP(rr, req)
 
 		assertStatusCode(t, rr.Code, http.StatusMethodNotAllowed)
+		assertAllow(t, rr, moviesMethods)
 	})
 
 	t.Run("options", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("OPTIONS", "/movies", nil)
 		if err != nil {
@@ -879,7 +1557,7 @@ func TestServeHTTP(t *testing.T) {
 	t.Run("unknown method", func(t *testing.T) {
 		repo := mocks.NewMoviesRepository()
 
-		handler := NewMoviesHandler(repo)
+		handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
 
 		req, err := http.NewRequest("UNKNOWN", "/movies", nil)
 		if err != nil {
@@ -891,9 +1569,91 @@ func TestServeHTTP(t *testing.T) {
 		handler.ServeHTTP(rr, req)
 
 		assertStatusCode(t, rr.Code, http.StatusMethodNotAllowed)
+		assertAllow(t, rr, moviesMethods)
+	})
+
+	t.Run("write without user", func(t *testing.T) {
+		for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			repo := mocks.NewMoviesRepository()
+
+			handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest(method, "/movies/550e8400-e29b-41d4-a716-446655440000", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assertStatusCode(t, rr.Code, http.StatusUnauthorized)
+
+			if rr.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("missing WWW-Authenticate header for %s", method)
+			}
+		}
+	})
+	t.Run("write with insufficient role", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			user   *models.User
+		}{
+			{"member posts movies", http.MethodPost, "/movies", &mocks.User},
+			{"member patches movie", http.MethodPatch, "/movies/550e8400-e29b-41d4-a716-446655440000", &mocks.User},
+			{"member deletes movie", http.MethodDelete, "/movies/550e8400-e29b-41d4-a716-446655440000", &mocks.User},
+			{"editor replaces movies", http.MethodPut, "/movies", &mocks.Editor},
+		}
+
+		for _, test := range tests {
+			handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest(test.method, test.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = withUser(req, test.user)
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusForbidden {
+				t.Errorf("%s: wrong status code, got %d want %d", test.name, rr.Code, http.StatusForbidden)
+			}
+		}
+	})
+
+	t.Run("editor updates movie", func(t *testing.T) {
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		body := movieRequestBody(t, &mocks.Movie)
+
+		req, err := http.NewRequest("PUT", "/movies/"+mocks.Movie.ID.String(), body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.Editor)
+
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusNoContent)
 	})
 }
 
+var (
+	// Handlers under test log to nowhere to keep test output readable.
+	testLogger = utils.NewLogger(io.Discard, utils.LogFormatText)
+
+	testValidate = utils.NewValidator()
+)
+
 func assertStatusCode(t *testing.T, got, want int) {
 	t.Helper()
 
@@ -902,6 +1662,36 @@ func assertStatusCode(t *testing.T, got, want int) {
 	}
 }
 
+// Asserts a 405 response lists the allowed methods.
+func assertAllow(t *testing.T, rr *httptest.ResponseRecorder, want string) {
+	t.Helper()
+
+	if got := rr.Header().Get("Allow"); got != want {
+		t.Errorf("wrong Allow, got %q want %q", got, want)
+	}
+}
+
+// Asserts response is a problem matching its status code and returns it.
+func assertProblem(t *testing.T, rr *httptest.ResponseRecorder) problem.Details {
+	t.Helper()
+
+	if got := rr.Header().Get("Content-Type"); got != problem.ContentType {
+		t.Errorf("wrong content type, got %v want %v", got, problem.ContentType)
+	}
+
+	var details problem.Details
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &details); err != nil {
+		t.Fatal(err)
+	}
+
+	if details.Status != rr.Code {
+		t.Errorf("wrong problem status, got %d want %d", details.Status, rr.Code)
+	}
+
+	return details
+}
+
 func assertMovie(t *testing.T, got, want *models.Movie) {
 	t.Helper()
 
@@ -910,6 +1700,14 @@ func assertMovie(t *testing.T, got, want *models.Movie) {
 	}
 }
 
+// Returns copy of the mock movie without an id, as clients send new movies.
+func newMovie() *models.Movie {
+	movie := mocks.Movie
+	movie.ID = uuid.Nil
+
+	return &movie
+}
+
 func moviesRequestBody(t *testing.T, movie []*models.Movie) io.Reader {
 	t.Helper()
 
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// Number of items in a page when request does not set a limit.
+	defaultPageLimit = 20
+
+	// Largest number of items a request can ask for in one page.
+	maxPageLimit = 100
+)
+
+var errInvalidLimit = fmt.Errorf("limit must be a number between 1 and %d", maxPageLimit)
+
+// Returns page size asked for with the limit query parameter.
+func pageLimit(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+
+	if value == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, errors.Join(errInvalidLimit, err)
+	}
+
+	return limit, nil
+}
+
+// Adds a Link header pointing at the next page, keeping every other query
+// parameter of the request. Does nothing on the last page.
+func setNextPageLink(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+
+	query := r.URL.Query()
+	query.Set("cursor", nextCursor)
+
+	next := *r.URL
+	next.RawQuery = query.Encode()
+
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetNextPageLink(t *testing.T) {
+	t.Run("next page", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/movies?limit=2&cursor=old", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		setNextPageLink(rr, req, "new")
+
+		if got, want := rr.Header().Get("Link"), `</movies?cursor=new&limit=2>; rel="next"`; got != want {
+			t.Errorf("wrong Link, got %v want %v", got, want)
+		}
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/movies", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		setNextPageLink(rr, req, "")
+
+		if got := rr.Header().Get("Link"); got != "" {
+			t.Errorf("unexpected Link %v on last page", got)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"moviepin/patch"
+)
+
+const (
+	// ErrUnsupportedPatch is returned when PATCH body is not a supported patch.
+	ErrUnsupportedPatch = "patch must be " + patch.MergeContentType + " or " + patch.JSONContentType
+
+	// ErrMovieIDChanged is returned when an update changes the id of the movie.
+	ErrMovieIDChanged = "movie id cannot be changed"
+)
+
+// Value of Accept-Patch, lists the patch formats PATCH accepts.
+const acceptPatch = patch.MergeContentType + ", " + patch.JSONContentType
+
+// Returns the patch format of the body of r. Plain JSON and a missing
+// Content-Type are read as merge patches, which is what PATCH always accepted.
+func patchContentType(r *http.Request) (string, bool) {
+	header := r.Header.Get("Content-Type")
+
+	if header == "" {
+		return patch.MergeContentType, true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+
+	if err != nil {
+		return "", false
+	}
+
+	switch mediaType {
+	case patch.MergeContentType, "application/json":
+		return patch.MergeContentType, true
+	case patch.JSONContentType:
+		return patch.JSONContentType, true
+	}
+
+	return "", false
+}
+
+// Returns value with body, a patch of contentType, applied to its JSON.
+// Fields the patch adds that value does not have are errors.
+func applyPatch[T any](value T, contentType string, body []byte) (T, error) {
+	var patchedValue, zero T
+
+	doc, err := json.Marshal(value)
+
+	if err != nil {
+		return zero, err
+	}
+
+	var patched []byte
+
+	if contentType == patch.JSONContentType {
+		patched, err = patch.Apply(doc, body)
+	} else {
+		patched, err = patch.Merge(doc, body)
+	}
+
+	if err != nil {
+		return zero, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&patchedValue); err != nil {
+		return zero, err
+	}
+
+	return patchedValue, nil
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"moviepin/auth"
+	"moviepin/mocks"
+	"moviepin/models"
+	"moviepin/patch"
+)
+
+func TestApplyPatch(t *testing.T) {
+	t.Run("merge patch", func(t *testing.T) {
+		movie, err := applyPatch(mocks.Movie, patch.MergeContentType, []byte(`{"title":"Enemy","genre":"Mystery"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := mocks.Movie
+		want.Title = "Enemy"
+		want.Genre = "Mystery"
+
+		assertMovie(t, &movie, &want)
+	})
+
+	t.Run("json patch", func(t *testing.T) {
+		body := `[
+			{"op":"test","path":"/title","value":"` + mocks.Movie.Title + `"},
+			{"op":"copy","from":"/title","path":"/description"},
+			{"op":"replace","path":"/release_date","value":"2013-09-20T00:00:00Z"}
+		]`
+
+		movie, err := applyPatch(mocks.Movie, patch.JSONContentType, []byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if movie.Description != mocks.Movie.Title {
+			t.Errorf("wrong description, got %v want %v", movie.Description, mocks.Movie.Title)
+		}
+
+		if movie.ReleaseDate.Year() != 2013 {
+			t.Errorf("wrong release date, got %v", movie.ReleaseDate)
+		}
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := applyPatch(mocks.Movie, patch.MergeContentType, []byte(`{"rating":5}`))
+
+		if err == nil || !strings.Contains(err.Error(), `unknown field "rating"`) {
+			t.Errorf("wrong error, got %v", err)
+		}
+	})
+
+	t.Run("list merge patch", func(t *testing.T) {
+		list, err := applyPatch(mocks.List, patch.MergeContentType, []byte(`{"visibility":"unlisted","slug":"wet-weekend"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := mocks.List
+		want.Visibility = models.ListVisibilityUnlisted
+		want.Slug = "wet-weekend"
+
+		if list != want {
+			t.Errorf("wrong list, got %+v want %+v", list, want)
+		}
+	})
+
+	t.Run("list json patch", func(t *testing.T) {
+		body := `[
+			{"op":"test","path":"/visibility","value":"` + mocks.List.Visibility + `"},
+			{"op":"replace","path":"/visibility","value":"private"},
+			{"op":"replace","path":"/slug","value":"wet-weekend"}
+		]`
+
+		list, err := applyPatch(mocks.List, patch.JSONContentType, []byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if list.Visibility != models.ListVisibilityPrivate || list.Slug != "wet-weekend" {
+			t.Errorf("wrong list, got %+v", list)
+		}
+
+		if list.Title != mocks.List.Title {
+			t.Errorf("wrong title, got %v want %v", list.Title, mocks.List.Title)
+		}
+	})
+
+	t.Run("list unknown field", func(t *testing.T) {
+		_, err := applyPatch(mocks.List, patch.MergeContentType, []byte(`{"items":[]}`))
+
+		if err == nil || !strings.Contains(err.Error(), `unknown field "items"`) {
+			t.Errorf("wrong error, got %v", err)
+		}
+	})
+}
+
+func TestPatchMovieFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+		field       string
+	}{
+		{"merge patch", patch.MergeContentType, `{"title":"Enemy"}`, http.StatusNoContent, ""},
+		{"merge patch with charset", patch.MergeContentType + "; charset=utf-8", `{"title":"Enemy"}`, http.StatusNoContent, ""},
+		{"plain json", "application/json", `{"title":"Enemy"}`, http.StatusNoContent, ""},
+		{"merge patch clears field", patch.MergeContentType, `{"director":null}`, http.StatusBadRequest, "director"},
+		{"merge patch wrong type", patch.MergeContentType, `{"title":1}`, http.StatusBadRequest, "title"},
+		{"merge patch unknown field", patch.MergeContentType, `{"rating":5}`, http.StatusBadRequest, ""},
+		{"merge patch changes id", patch.MergeContentType, `{"id":"550e8400-e29b-41d4-a716-446655440001"}`, http.StatusBadRequest, ""},
+		{"json patch", patch.JSONContentType, `[{"op":"replace","path":"/title","value":"Enemy"}]`, http.StatusNoContent, ""},
+		{"json patch removes field", patch.JSONContentType, `[{"op":"remove","path":"/genre"}]`, http.StatusBadRequest, "genre"},
+		{"json patch failed test", patch.JSONContentType, `[{"op":"test","path":"/title","value":"Enemy"}]`, http.StatusConflict, ""},
+		{"json patch bad path", patch.JSONContentType, `[{"op":"replace","path":"/rating","value":5}]`, http.StatusBadRequest, ""},
+		{"json patch not an array", patch.JSONContentType, `{"title":"Enemy"}`, http.StatusBadRequest, ""},
+		{"unsupported type", "text/plain", `title=Enemy`, http.StatusUnsupportedMediaType, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewMoviesRepository()
+			repo.UpdateMovieError = nil
+
+			handler := NewMoviesHandler(repo, mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest("PATCH", moviePath, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", tt.contentType)
+
+			rr := httptest.NewRecorder()
+
+			handler.patchMovie(rr, req)
+
+			assertStatusCode(t, rr.Code, tt.want)
+
+			if tt.want == http.StatusNoContent {
+				return
+			}
+
+			details := assertProblem(t, rr)
+
+			if tt.field != "" && (len(details.Errors) != 1 || details.Errors[0].Field != tt.field) {
+				t.Errorf("wrong field errors, got %+v want %v", details.Errors, tt.field)
+			}
+
+			if tt.want == http.StatusUnsupportedMediaType && rr.Header().Get("Accept-Patch") != acceptPatch {
+				t.Errorf("wrong Accept-Patch, got %v want %v", rr.Header().Get("Accept-Patch"), acceptPatch)
+			}
+		})
+	}
+}
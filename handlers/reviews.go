@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"moviepin/auth"
+	"moviepin/db/movies"
+	"moviepin/db/reviews"
+	"moviepin/models"
+	"moviepin/problem"
+	"moviepin/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+const (
+	// ErrReviewNotExists is returned when review does not exist.
+	ErrReviewNotExists = "review does not exist"
+
+	// ErrFailedToGetReview is returned when failed to get review.
+	ErrFailedToGetReview = "failed to get review"
+
+	// ErrFailedToGetReviews is returned when failed to get reviews.
+	ErrFailedToGetReviews = "failed to get reviews"
+
+	// ErrFailedToAddReview is returned when failed to add review.
+	ErrFailedToAddReview = "failed to add review"
+
+	// ErrFailedToUpdateReview is returned when failed to update review.
+	ErrFailedToUpdateReview = "failed to update review"
+
+	// ErrFailedToDeleteReview is returned when failed to delete review.
+	ErrFailedToDeleteReview = "failed to delete review"
+
+	// ErrNotReviewAuthor is returned when user changes a review of someone else.
+	ErrNotReviewAuthor = "forbidden: only the author or a moderator can change a review"
+)
+
+type ReviewsHandler struct {
+	movies   movies.MoviesRepository
+	reviews  reviews.ReviewsRepository
+	policy   *auth.Policy
+	logger   *slog.Logger
+	validate *validator.Validate
+}
+
+// Returns a new ReviewsHandler.
+func NewReviewsHandler(movies movies.MoviesRepository, reviews reviews.ReviewsRepository, policy *auth.Policy, logger *slog.Logger, validate *validator.Validate) *ReviewsHandler {
+	return &ReviewsHandler{movies: movies, reviews: reviews, policy: policy, logger: logger, validate: validate}
+}
+
+// Responds with all the reviews of a movie.
+func (rh ReviewsHandler) getReviews(w http.ResponseWriter, r *http.Request) {
+	movieID, _, err := utils.GetReviewIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		rh.logger.InfoContext(r.Context(), ErrFailedToGetReviews, "error", err)
+		problem.WriteInvalid(w, ErrFailedToGetReviews, err)
+		return
+	}
+
+	if err = rh.validate.Var(movieID, "required,uuid"); err != nil {
+		rh.logger.InfoContext(r.Context(), ErrFailedToGetReviews, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToGetReviews, problem.FieldErrors(err, "movie_id"))
+		return
+	}
+
+	if _, err = rh.movies.GetMovie(r.Context(), movieID); err != nil {
+		if err == movies.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrNotExists)
+			return
+		}
+
+		rh.logger.ErrorContext(r.Context(), ErrFailedToGetReviews, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetReviews)
+		return
+	}
+
+	reviews, err := rh.reviews.GetReviews(r.Context(), movieID)
+
+	if err != nil {
+		rh.logger.ErrorContext(r.Context(), ErrFailedToGetReviews, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetReviews)
+		return
+	}
+
+	reviewsJson, err := json.Marshal(reviews)
+
+	if err != nil {
+		rh.logger.ErrorContext(r.Context(), ErrFailedToGetReviews, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetReviews)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reviewsJson)
+}
+
+// Responds with details of particular review.
+func (rh ReviewsHandler) getReview(w http.ResponseWriter, r *http.Request) {
+	movieID, reviewID, err := utils.GetReviewIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		rh.logger.InfoContext(r.Context(), ErrFailedToGetReview, "error", err)
+		problem.WriteInvalid(w, ErrFailedToGetReview, err)
+		return
+	}
+
+	if fieldErrors := rh.validateReviewIDs(movieID, reviewID); len(fieldErrors) > 0 {
+		problem.WriteFieldErrors(w, ErrFailedToGetReview, fieldErrors)
+		return
+	}
+
+	review, err := rh.reviews.GetReview(r.Context(), movieID, reviewID)
+
+	if err == reviews.ErrNotExists {
+		problem.Write(w, http.StatusNotFound, ErrReviewNotExists)
+		return
+	}
+
+	if err != nil {
+		rh.logger.ErrorContext(r.Context(), ErrFailedToGetReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetReview)
+		return
+	}
+
+	reviewJson, err := json.Marshal(review)
+
+	if err != nil {
+		rh.logger.ErrorContext(r.Context(), ErrFailedToGetReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetReview)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reviewJson)
+}
+
+// Adds review sent in request to a movie.
+func (rh ReviewsHandler) postReview(w http.ResponseWriter, r *http.Request) {
+	user, ok := authorize(w, r, rh.policy, auth.PermWriteReviews)
+
+	if !ok {
+		return
+	}
+
+	movieID, _, err := utils.GetReviewIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		rh.logger.InfoContext(r.Context(), ErrFailedToAddReview, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddReview, err)
+		return
+	}
+
+	if err = rh.validate.Var(movieID, "required,uuid"); err != nil {
+		rh.logger.InfoContext(r.Context(), ErrFailedToAddReview, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToAddReview, problem.FieldErrors(err, "movie_id"))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		rh.logger.ErrorContext(r.Context(), ErrFailedToAddReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddReview)
+		return
+	}
+
+	var review models.Review
+
+	if err = json.Unmarshal(body, &review); err != nil {
+		rh.logger.InfoContext(r.Context(), ErrFailedToAddReview, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddReview, err)
+		return
+	}
+
+	if _, err = rh.movies.GetMovie(r.Context(), movieID); err != nil {
+		if err == movies.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrNotExists)
+			return
+		}
+
+		rh.logger.ErrorContext(r.Context(), ErrFailedToAddReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddReview)
+		return
+	}
+
+	// Fields managed by the server are never taken from the request.
+	now := time.Now().UTC()
+	review.ID = uuid.New()
+	review.UserID = user.ID
+	review.MovieID = uuid.MustParse(movieID)
+	review.CreatedAt = now
+	review.UpdatedAt = now
+
+	if err = rh.validate.Struct(review); err != nil {
+		rh.logger.InfoContext(r.Context(), ErrFailedToAddReview, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddReview, err)
+		return
+	}
+
+	if err = rh.reviews.AddReview(r.Context(), review); err != nil {
+		rh.logger.ErrorContext(r.Context(), ErrFailedToAddReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddReview)
+		return
+	}
+
+	reviewJson, err := json.Marshal(review)
+
+	if err != nil {
+		rh.logger.ErrorContext(r.Context(), ErrFailedToAddReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddReview)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/movies/"+movieID+"/reviews/"+review.ID.String())
+	w.WriteHeader(http.StatusCreated)
+	w.Write(reviewJson)
+}
+
+// Updates rating and text of a particular review.
+func (rh ReviewsHandler) putReview(w http.ResponseWriter, r *http.Request) {
+	user, ok := authorize(w, r, rh.policy, auth.PermWriteReviews)
+
+	if !ok {
+		return
+	}
+
+	movieID, reviewID, err := utils.GetReviewIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrReviewNotExists)
+		return
+	}
+
+	if fieldErrors := rh.validateReviewIDs(movieID, reviewID); len(fieldErrors) > 0 {
+		problem.WriteFieldErrors(w, ErrFailedToUpdateReview, fieldErrors)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		rh.logger.ErrorContext(r.Context(), ErrFailedToUpdateReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateReview)
+		return
+	}
+
+	var update models.Review
+
+	if err = json.Unmarshal(body, &update); err != nil {
+		rh.logger.InfoContext(r.Context(), ErrFailedToUpdateReview, "error", err)
+		problem.WriteInvalid(w, ErrFailedToUpdateReview, err)
+		return
+	}
+
+	existingReview, err := rh.reviews.GetReview(r.Context(), movieID, reviewID)
+
+	if err != nil {
+		if err == reviews.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrReviewNotExists)
+			return
+		}
+
+		rh.logger.ErrorContext(r.Context(), ErrFailedToUpdateReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateReview)
+		return
+	}
+
+	if existingReview.UserID != user.ID && !rh.policy.Allows(user, auth.PermModerateReviews) {
+		problem.Write(w, http.StatusForbidden, ErrNotReviewAuthor)
+		return
+	}
+
+	existingReview.Rating = update.Rating
+	existingReview.ReviewText = update.ReviewText
+	existingReview.UpdatedAt = time.Now().UTC()
+
+	if err = rh.validate.Struct(existingReview); err != nil {
+		rh.logger.InfoContext(r.Context(), ErrFailedToUpdateReview, "error", err)
+		problem.WriteInvalid(w, ErrFailedToUpdateReview, err)
+		return
+	}
+
+	err = rh.reviews.UpdateReview(r.Context(), movieID, reviewID, *existingReview)
+
+	if err != nil {
+		if err == reviews.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrReviewNotExists)
+			return
+		}
+
+		rh.logger.ErrorContext(r.Context(), ErrFailedToUpdateReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToUpdateReview)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deletes a particular review.
+func (rh ReviewsHandler) deleteReview(w http.ResponseWriter, r *http.Request) {
+	user, ok := authorize(w, r, rh.policy, auth.PermWriteReviews)
+
+	if !ok {
+		return
+	}
+
+	movieID, reviewID, err := utils.GetReviewIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrReviewNotExists)
+		return
+	}
+
+	if fieldErrors := rh.validateReviewIDs(movieID, reviewID); len(fieldErrors) > 0 {
+		problem.WriteFieldErrors(w, ErrFailedToDeleteReview, fieldErrors)
+		return
+	}
+
+	existingReview, err := rh.reviews.GetReview(r.Context(), movieID, reviewID)
+
+	if err != nil {
+		if err == reviews.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrReviewNotExists)
+			return
+		}
+
+		rh.logger.ErrorContext(r.Context(), ErrFailedToDeleteReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToDeleteReview)
+		return
+	}
+
+	if existingReview.UserID != user.ID && !rh.policy.Allows(user, auth.PermModerateReviews) {
+		problem.Write(w, http.StatusForbidden, ErrNotReviewAuthor)
+		return
+	}
+
+	err = rh.reviews.DeleteReview(r.Context(), movieID, reviewID)
+
+	if err != nil {
+		if err == reviews.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrReviewNotExists)
+			return
+		}
+
+		rh.logger.ErrorContext(r.Context(), ErrFailedToDeleteReview, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToDeleteReview)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Methods the reviews routes allow, advertised by Options and 405 responses.
+const reviewsMethods = "GET, POST, PUT, DELETE, OPTIONS"
+
+// Responds with allowed methods.
+func (rh ReviewsHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Methods", reviewsMethods)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (rh ReviewsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, reviewID, err := utils.GetReviewIDsFromPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrReviewNotExists)
+		return
+	}
+
+	isCollectionPath := reviewID == ""
+
+	switch r.Method {
+	case http.MethodGet:
+		if isCollectionPath {
+			rh.getReviews(w, r)
+		} else {
+			rh.getReview(w, r)
+		}
+	case http.MethodPost:
+		if isCollectionPath {
+			rh.postReview(w, r)
+		} else {
+			writeMethodNotAllowed(w, r, reviewsMethods)
+		}
+	case http.MethodPut:
+		if isCollectionPath {
+			writeMethodNotAllowed(w, r, reviewsMethods)
+		} else {
+			rh.putReview(w, r)
+		}
+	case http.MethodDelete:
+		if isCollectionPath {
+			writeMethodNotAllowed(w, r, reviewsMethods)
+		} else {
+			rh.deleteReview(w, r)
+		}
+	case http.MethodOptions:
+		rh.Options(w, r)
+	default:
+		writeMethodNotAllowed(w, r, reviewsMethods)
+	}
+}
+
+// Validates movie id and review id taken from path, returns the invalid ones.
+func (rh ReviewsHandler) validateReviewIDs(movieID string, reviewID string) []problem.FieldError {
+	var fieldErrors []problem.FieldError
+
+	if err := rh.validate.Var(movieID, "required,uuid"); err != nil {
+		fieldErrors = append(fieldErrors, problem.FieldErrors(err, "movie_id")...)
+	}
+
+	if err := rh.validate.Var(reviewID, "required,uuid"); err != nil {
+		fieldErrors = append(fieldErrors, problem.FieldErrors(err, "review_id")...)
+	}
+
+	return fieldErrors
+}
@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"moviepin/auth"
+	"moviepin/db/movies"
+	"moviepin/db/reviews"
+	"moviepin/mocks"
+	"moviepin/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	reviewsPath = "/movies/6ba7b810-9dad-11d1-80b4-00c04fd430c8/reviews"
+	reviewPath  = reviewsPath + "/7c9e6679-7425-40de-944b-e07fc1f90ae7"
+)
+
+func TestGetReviews(t *testing.T) {
+	t.Run("get reviews", func(t *testing.T) {
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", reviewsPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getReviews(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+
+		body, err := io.ReadAll(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var reviews []*models.Review
+
+		if err := json.Unmarshal(body, &reviews); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(reviews) != 1 {
+			t.Fatalf("wrong number of reviews, got %v want %v", len(reviews), 1)
+		}
+
+		assertReview(t, reviews[0], &mocks.Review)
+	})
+
+	t.Run("get reviews wrong path", func(t *testing.T) {
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", "/movies/1/reviews", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getReviews(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+	})
+
+	t.Run("get reviews movie not found", func(t *testing.T) {
+		moviesRepo := mocks.NewMoviesRepository()
+		moviesRepo.GetMovieError = movies.ErrNotExists
+
+		handler := NewReviewsHandler(moviesRepo, mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", reviewsPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getReviews(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusNotFound)
+	})
+
+	t.Run("get reviews error", func(t *testing.T) {
+		reviewsRepo := mocks.NewReviewsRepository()
+		reviewsRepo.GetReviewsError = errors.New("error")
+
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), reviewsRepo, auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", reviewsPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getReviews(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusInternalServerError)
+	})
+}
+
+func TestGetReview(t *testing.T) {
+	t.Run("get review", func(t *testing.T) {
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", reviewPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+
+		var review models.Review
+
+		if err := json.Unmarshal(rr.Body.Bytes(), &review); err != nil {
+			t.Fatal(err)
+		}
+
+		assertReview(t, &review, &mocks.Review)
+	})
+
+	t.Run("get review wrong path", func(t *testing.T) {
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", reviewsPath+"/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+	})
+
+	t.Run("get review not found", func(t *testing.T) {
+		reviewsRepo := mocks.NewReviewsRepository()
+		reviewsRepo.GetReviewError = reviews.ErrNotExists
+
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), reviewsRepo, auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("GET", reviewPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusNotFound)
+	})
+}
+
+func TestPostReview(t *testing.T) {
+	t.Run("post review", func(t *testing.T) {
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("POST", reviewsPath, reviewRequestBody(t, &mocks.Review))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.User)
+
+		rr := httptest.NewRecorder()
+
+		handler.postReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusCreated)
+
+		if rr.Header().Get("Location") == "" {
+			t.Errorf("missing Location header")
+		}
+	})
+
+	t.Run("post review invalid rating", func(t *testing.T) {
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		review := mocks.Review
+		review.Rating = 6
+
+		req, err := http.NewRequest("POST", reviewsPath, reviewRequestBody(t, &review))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.User)
+
+		rr := httptest.NewRecorder()
+
+		handler.postReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+
+		details := assertProblem(t, rr)
+
+		if len(details.Errors) != 1 {
+			t.Fatalf("wrong number of field errors, got %v want %v", len(details.Errors), 1)
+		}
+
+		if details.Errors[0].Field != "rating" || details.Errors[0].Rule != "max" {
+			t.Errorf("wrong field error, got %+v", details.Errors[0])
+		}
+	})
+
+	t.Run("post review movie not found", func(t *testing.T) {
+		moviesRepo := mocks.NewMoviesRepository()
+		moviesRepo.GetMovieError = movies.ErrNotExists
+
+		handler := NewReviewsHandler(moviesRepo, mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("POST", reviewsPath, reviewRequestBody(t, &mocks.Review))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.User)
+
+		rr := httptest.NewRecorder()
+
+		handler.postReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusNotFound)
+	})
+
+	t.Run("post review error", func(t *testing.T) {
+		reviewsRepo := mocks.NewReviewsRepository()
+		reviewsRepo.AddReviewError = errors.New("error")
+
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), reviewsRepo, auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("POST", reviewsPath, reviewRequestBody(t, &mocks.Review))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.User)
+
+		rr := httptest.NewRecorder()
+
+		handler.postReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusInternalServerError)
+	})
+}
+
+func TestPutReview(t *testing.T) {
+	t.Run("put review", func(t *testing.T) {
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("PUT", reviewPath, reviewRequestBody(t, &mocks.Review))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.User)
+
+		rr := httptest.NewRecorder()
+
+		handler.putReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusNoContent)
+	})
+
+	t.Run("put review of another user", func(t *testing.T) {
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("PUT", reviewPath, reviewRequestBody(t, &mocks.Review))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		other := mocks.User
+		other.ID = uuid.New()
+
+		req = withUser(req, &other)
+
+		rr := httptest.NewRecorder()
+
+		handler.putReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusForbidden)
+	})
+
+	t.Run("put review of another user as admin", func(t *testing.T) {
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("PUT", reviewPath, reviewRequestBody(t, &mocks.Review))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.Admin)
+
+		rr := httptest.NewRecorder()
+
+		handler.putReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusNoContent)
+	})
+
+	t.Run("put review not found", func(t *testing.T) {
+		reviewsRepo := mocks.NewReviewsRepository()
+		reviewsRepo.GetReviewError = reviews.ErrNotExists
+
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), reviewsRepo, auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("PUT", reviewPath, reviewRequestBody(t, &mocks.Review))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.User)
+
+		rr := httptest.NewRecorder()
+
+		handler.putReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusNotFound)
+	})
+
+	t.Run("put review error", func(t *testing.T) {
+		reviewsRepo := mocks.NewReviewsRepository()
+		reviewsRepo.UpdateReviewError = errors.New("error")
+
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), reviewsRepo, auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("PUT", reviewPath, reviewRequestBody(t, &mocks.Review))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.User)
+
+		rr := httptest.NewRecorder()
+
+		handler.putReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusInternalServerError)
+	})
+}
+
+func TestDeleteReview(t *testing.T) {
+	t.Run("delete review", func(t *testing.T) {
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("DELETE", reviewPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.User)
+
+		rr := httptest.NewRecorder()
+
+		handler.deleteReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusNoContent)
+	})
+
+	t.Run("delete review not found", func(t *testing.T) {
+		reviewsRepo := mocks.NewReviewsRepository()
+		reviewsRepo.DeleteReviewError = reviews.ErrNotExists
+
+		handler := NewReviewsHandler(mocks.NewMoviesRepository(), reviewsRepo, auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest("DELETE", reviewPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req = withUser(req, &mocks.User)
+
+		rr := httptest.NewRecorder()
+
+		handler.deleteReview(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusNotFound)
+	})
+}
+
+func TestReviewsServeHTTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   io.Reader
+		user   *models.User
+		want   int
+	}{
+		{"get reviews path", http.MethodGet, reviewsPath, nil, nil, http.StatusOK},
+		{"get review path", http.MethodGet, reviewPath, nil, nil, http.StatusOK},
+		{"post reviews path", http.MethodPost, reviewsPath, reviewRequestBody(t, &mocks.Review), &mocks.User, http.StatusCreated},
+		{"post reviews path without user", http.MethodPost, reviewsPath, reviewRequestBody(t, &mocks.Review), nil, http.StatusUnauthorized},
+		{"post review path", http.MethodPost, reviewPath, nil, &mocks.User, http.StatusMethodNotAllowed},
+		{"put review path", http.MethodPut, reviewPath, reviewRequestBody(t, &mocks.Review), &mocks.User, http.StatusNoContent},
+		{"put reviews path", http.MethodPut, reviewsPath, nil, &mocks.User, http.StatusMethodNotAllowed},
+		{"delete review path", http.MethodDelete, reviewPath, nil, &mocks.User, http.StatusNoContent},
+		{"delete review path without user", http.MethodDelete, reviewPath, nil, nil, http.StatusUnauthorized},
+		{"delete reviews path", http.MethodDelete, reviewsPath, nil, &mocks.User, http.StatusMethodNotAllowed},
+		{"options", http.MethodOptions, reviewsPath, nil, nil, http.StatusNoContent},
+		{"unknown method", "UNKNOWN", reviewsPath, nil, nil, http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest(test.method, test.path, test.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.user != nil {
+				req = withUser(req, test.user)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assertStatusCode(t, rr.Code, test.want)
+
+			if test.want == http.StatusMethodNotAllowed {
+				assertAllow(t, rr, reviewsMethods)
+			}
+		})
+	}
+}
+
+func assertReview(t *testing.T, got, want *models.Review) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong review, got %v want %v", got, want)
+	}
+}
+
+func TestPostReviewRatings(t *testing.T) {
+	tests := []struct {
+		name   string
+		rating string
+		want   int
+	}{
+		{"lowest", "1", http.StatusCreated},
+		{"highest", "5", http.StatusCreated},
+		{"zero", "0", http.StatusBadRequest},
+		{"above scale", "6", http.StatusBadRequest},
+		{"fractional", "3.5", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewReviewsHandler(mocks.NewMoviesRepository(), mocks.NewReviewsRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			body := strings.NewReader(`{"rating":` + tt.rating + `,"review_text":"Worth a rewatch"}`)
+
+			req, err := http.NewRequest("POST", reviewsPath, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.postReview(rr, withUser(req, &mocks.User))
+
+			assertStatusCode(t, rr.Code, tt.want)
+		})
+	}
+}
+
+func reviewRequestBody(t *testing.T, review *models.Review) io.Reader {
+	t.Helper()
+
+	reviewJSON, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.NewBuffer(reviewJSON)
+}
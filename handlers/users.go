@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"moviepin/auth"
+	"moviepin/db/users"
+	"moviepin/models"
+	"moviepin/problem"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// ErrFailedToAddUser is returned when failed to register user.
+	ErrFailedToAddUser = "failed to register user"
+
+	// ErrUserAlreadyExists is returned when username or email is taken.
+	ErrUserAlreadyExists = "username or email already taken"
+)
+
+type UsersHandler struct {
+	db       users.UsersRepository
+	logger   *slog.Logger
+	validate *validator.Validate
+}
+
+// Returns a new UsersHandler.
+func NewUsersHandler(db users.UsersRepository, logger *slog.Logger, validate *validator.Validate) *UsersHandler {
+	return &UsersHandler{db: db, logger: logger, validate: validate}
+}
+
+// Registers user sent in request.
+func (uh UsersHandler) postUser(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		uh.logger.ErrorContext(r.Context(), ErrFailedToAddUser, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddUser)
+		return
+	}
+
+	var registration models.Registration
+
+	if err = json.Unmarshal(body, &registration); err != nil {
+		uh.logger.InfoContext(r.Context(), ErrFailedToAddUser, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddUser, err)
+		return
+	}
+
+	registration.Email = strings.ToLower(strings.TrimSpace(registration.Email))
+
+	if err = uh.validate.Struct(registration); err != nil {
+		uh.logger.InfoContext(r.Context(), ErrFailedToAddUser, "error", err)
+		problem.WriteInvalid(w, ErrFailedToAddUser, err)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(registration.Password), bcrypt.DefaultCost)
+
+	if err != nil {
+		uh.logger.ErrorContext(r.Context(), ErrFailedToAddUser, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddUser)
+		return
+	}
+
+	user := models.User{
+		ID:           uuid.New(),
+		Username:     registration.Username,
+		Email:        registration.Email,
+		Role:         auth.RoleMember,
+		PasswordHash: string(hash),
+	}
+
+	if err = uh.db.AddUser(r.Context(), user); err != nil {
+		if err == users.ErrAlreadyExists {
+			problem.Write(w, http.StatusConflict, ErrUserAlreadyExists)
+			return
+		}
+
+		uh.logger.ErrorContext(r.Context(), ErrFailedToAddUser, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddUser)
+		return
+	}
+
+	userJson, err := json.Marshal(user)
+
+	if err != nil {
+		uh.logger.ErrorContext(r.Context(), ErrFailedToAddUser, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToAddUser)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(userJson)
+}
+
+// Methods the users route allows, advertised by Options and 405 responses.
+const usersMethods = "POST, OPTIONS"
+
+// Responds with allowed methods.
+func (uh UsersHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Methods", usersMethods)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (uh UsersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		uh.postUser(w, r)
+	case http.MethodOptions:
+		uh.Options(w, r)
+	default:
+		writeMethodNotAllowed(w, r, usersMethods)
+	}
+}
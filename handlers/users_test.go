@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"moviepin/db/users"
+	"moviepin/mocks"
+	"moviepin/models"
+)
+
+func TestPostUser(t *testing.T) {
+	registration := models.Registration{
+		Username: "dummyuser2",
+		Email:    "Dummy2@Email.com",
+		Password: "password123",
+	}
+
+	t.Run("post user", func(t *testing.T) {
+		handler := NewUsersHandler(mocks.NewUsersRepository(), testLogger, testValidate)
+
+		req, err := http.NewRequest("POST", "/users", jsonRequestBody(t, registration))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postUser(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusCreated)
+
+		body := rr.Body.String()
+
+		if strings.Contains(body, "password") {
+			t.Errorf("response leaks password: %s", body)
+		}
+
+		var user models.User
+
+		if err := json.Unmarshal([]byte(body), &user); err != nil {
+			t.Fatal(err)
+		}
+
+		if user.Email != "dummy2@email.com" {
+			t.Errorf("wrong email, got %v want %v", user.Email, "dummy2@email.com")
+		}
+	})
+
+	t.Run("post user short password", func(t *testing.T) {
+		handler := NewUsersHandler(mocks.NewUsersRepository(), testLogger, testValidate)
+
+		invalid := registration
+		invalid.Password = "short"
+
+		req, err := http.NewRequest("POST", "/users", jsonRequestBody(t, invalid))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postUser(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusBadRequest)
+	})
+
+	t.Run("post user already exists", func(t *testing.T) {
+		repo := mocks.NewUsersRepository()
+		repo.AddUserError = users.ErrAlreadyExists
+
+		handler := NewUsersHandler(repo, testLogger, testValidate)
+
+		req, err := http.NewRequest("POST", "/users", jsonRequestBody(t, registration))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postUser(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusConflict)
+	})
+
+	t.Run("post user error", func(t *testing.T) {
+		repo := mocks.NewUsersRepository()
+		repo.AddUserError = errors.New("error")
+
+		handler := NewUsersHandler(repo, testLogger, testValidate)
+
+		req, err := http.NewRequest("POST", "/users", jsonRequestBody(t, registration))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.postUser(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusInternalServerError)
+	})
+}
+
+func jsonRequestBody(t *testing.T, v any) io.Reader {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.NewBuffer(b)
+}
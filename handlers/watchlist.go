@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"moviepin/auth"
+	"moviepin/db/watchlist"
+	"moviepin/models"
+	"moviepin/problem"
+	"moviepin/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+const (
+	// ErrWatchStatusNotExists is returned when user gave the movie no status.
+	ErrWatchStatusNotExists = "watch status does not exist"
+
+	// ErrFailedToGetWatchStatus is returned when failed to get watch status.
+	ErrFailedToGetWatchStatus = "failed to get watch status"
+
+	// ErrFailedToSetWatchStatus is returned when failed to set watch status.
+	ErrFailedToSetWatchStatus = "failed to set watch status"
+
+	// ErrFailedToDeleteWatchStatus is returned when failed to clear watch status.
+	ErrFailedToDeleteWatchStatus = "failed to clear watch status"
+
+	// ErrFailedToGetWatchlist is returned when failed to get watchlist.
+	ErrFailedToGetWatchlist = "failed to get watchlist"
+
+	// ErrNotWatchlistOwner is returned when user reads a watchlist of someone else.
+	ErrNotWatchlistOwner = "forbidden: only the owner can read a watchlist"
+)
+
+type WatchlistHandler struct {
+	watchlist watchlist.WatchlistRepository
+	policy    *auth.Policy
+	logger    *slog.Logger
+	validate  *validator.Validate
+}
+
+// Returns a new WatchlistHandler.
+func NewWatchlistHandler(watchlist watchlist.WatchlistRepository, policy *auth.Policy, logger *slog.Logger, validate *validator.Validate) *WatchlistHandler {
+	return &WatchlistHandler{watchlist: watchlist, policy: policy, logger: logger, validate: validate}
+}
+
+// Responds with the status the caller gave a movie.
+func (wh WatchlistHandler) getStatus(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireUser(w, r)
+
+	if !ok {
+		return
+	}
+
+	movieID, ok := wh.statusMovieID(w, r, ErrFailedToGetWatchStatus)
+
+	if !ok {
+		return
+	}
+
+	status, err := wh.watchlist.GetStatus(r.Context(), user.ID.String(), movieID)
+
+	if err == watchlist.ErrNotExists {
+		problem.Write(w, http.StatusNotFound, ErrWatchStatusNotExists)
+		return
+	}
+
+	if err != nil {
+		wh.logger.ErrorContext(r.Context(), ErrFailedToGetWatchStatus, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetWatchStatus)
+		return
+	}
+
+	statusJson, err := json.Marshal(status)
+
+	if err != nil {
+		wh.logger.ErrorContext(r.Context(), ErrFailedToGetWatchStatus, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetWatchStatus)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(statusJson)
+}
+
+// Sets the status the caller gives a movie, replacing the one they gave
+// before. A movie marked watched without a date was watched today.
+func (wh WatchlistHandler) putStatus(w http.ResponseWriter, r *http.Request) {
+	user, ok := authorize(w, r, wh.policy, auth.PermWriteWatchlist)
+
+	if !ok {
+		return
+	}
+
+	movieID, ok := wh.statusMovieID(w, r, ErrFailedToSetWatchStatus)
+
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		wh.logger.ErrorContext(r.Context(), ErrFailedToSetWatchStatus, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToSetWatchStatus)
+		return
+	}
+
+	var status models.WatchStatus
+
+	if err = json.Unmarshal(body, &status); err != nil {
+		wh.logger.InfoContext(r.Context(), ErrFailedToSetWatchStatus, "error", err)
+		problem.WriteInvalid(w, ErrFailedToSetWatchStatus, err)
+		return
+	}
+
+	if err = wh.validate.Struct(status); err != nil {
+		wh.logger.InfoContext(r.Context(), ErrFailedToSetWatchStatus, "error", err)
+		problem.WriteInvalid(w, ErrFailedToSetWatchStatus, err)
+		return
+	}
+
+	if status.Status != models.WatchStatusWatched && status.WatchedAt != nil {
+		problem.WriteFieldErrors(w, ErrFailedToSetWatchStatus, []problem.FieldError{{
+			Field:   "watched_at",
+			Rule:    "excluded_unless",
+			Param:   "status " + models.WatchStatusWatched,
+			Message: "watched_at can only be set when status is " + models.WatchStatusWatched,
+		}})
+		return
+	}
+
+	// Fields managed by the server are never taken from the request.
+	now := time.Now().UTC()
+	status.UserID = user.ID
+	status.MovieID = uuid.MustParse(movieID)
+	status.UpdatedAt = now
+	status.Movie = nil
+
+	if status.Status == models.WatchStatusWatched {
+		watchedAt := now
+
+		if status.WatchedAt != nil {
+			watchedAt = *status.WatchedAt
+		}
+
+		// Only the day is kept, as the client wrote it.
+		watchedAt = time.Date(watchedAt.Year(), watchedAt.Month(), watchedAt.Day(), 0, 0, 0, 0, time.UTC)
+		status.WatchedAt = &watchedAt
+	}
+
+	if err = wh.watchlist.SetStatus(r.Context(), status); err != nil {
+		if err == watchlist.ErrMovieNotExists {
+			problem.Write(w, http.StatusNotFound, ErrNotExists)
+			return
+		}
+
+		wh.logger.ErrorContext(r.Context(), ErrFailedToSetWatchStatus, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToSetWatchStatus)
+		return
+	}
+
+	statusJson, err := json.Marshal(status)
+
+	if err != nil {
+		wh.logger.ErrorContext(r.Context(), ErrFailedToSetWatchStatus, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToSetWatchStatus)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(statusJson)
+}
+
+// Clears the status the caller gave a movie.
+func (wh WatchlistHandler) deleteStatus(w http.ResponseWriter, r *http.Request) {
+	user, ok := authorize(w, r, wh.policy, auth.PermWriteWatchlist)
+
+	if !ok {
+		return
+	}
+
+	movieID, ok := wh.statusMovieID(w, r, ErrFailedToDeleteWatchStatus)
+
+	if !ok {
+		return
+	}
+
+	if err := wh.watchlist.DeleteStatus(r.Context(), user.ID.String(), movieID); err != nil {
+		if err == watchlist.ErrNotExists {
+			problem.Write(w, http.StatusNotFound, ErrWatchStatusNotExists)
+			return
+		}
+
+		wh.logger.ErrorContext(r.Context(), ErrFailedToDeleteWatchStatus, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToDeleteWatchStatus)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Responds with a page of the statuses of the caller and their movies, most
+// recently changed first, optionally only those with the status query
+// parameter.
+func (wh WatchlistHandler) getWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireUser(w, r)
+
+	if !ok {
+		return
+	}
+
+	userID, err := utils.GetUserIDFromWatchlistPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrWatchStatusNotExists)
+		return
+	}
+
+	if err = wh.validate.Var(userID, "required,uuid"); err != nil {
+		wh.logger.InfoContext(r.Context(), ErrFailedToGetWatchlist, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToGetWatchlist, problem.FieldErrors(err, "user_id"))
+		return
+	}
+
+	if userID != user.ID.String() {
+		problem.Write(w, http.StatusForbidden, ErrNotWatchlistOwner)
+		return
+	}
+
+	query := watchlist.WatchlistQuery{
+		Cursor: r.URL.Query().Get("cursor"),
+		Status: r.URL.Query().Get("status"),
+	}
+
+	if err = wh.validate.Var(query.Status, "omitempty,oneof=want_to_watch watching watched"); err != nil {
+		wh.logger.InfoContext(r.Context(), ErrFailedToGetWatchlist, "error", err)
+		problem.WriteFieldErrors(w, ErrFailedToGetWatchlist, problem.FieldErrors(err, "status"))
+		return
+	}
+
+	if query.Limit, err = pageLimit(r); err != nil {
+		wh.logger.InfoContext(r.Context(), ErrFailedToGetWatchlist, "error", err)
+		problem.WriteInvalid(w, ErrFailedToGetWatchlist, err)
+		return
+	}
+
+	page, err := wh.watchlist.GetWatchlistPage(r.Context(), userID, query)
+
+	if err == watchlist.ErrInvalidCursor {
+		wh.logger.InfoContext(r.Context(), ErrFailedToGetWatchlist, "error", err)
+		problem.WriteInvalid(w, ErrFailedToGetWatchlist, err)
+		return
+	}
+
+	if err != nil {
+		wh.logger.ErrorContext(r.Context(), ErrFailedToGetWatchlist, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetWatchlist)
+		return
+	}
+
+	pageJson, err := json.Marshal(page)
+
+	if err != nil {
+		wh.logger.ErrorContext(r.Context(), ErrFailedToGetWatchlist, "error", err)
+		problem.Write(w, problem.FailureStatus(r.Context(), err), ErrFailedToGetWatchlist)
+		return
+	}
+
+	setNextPageLink(w, r, page.NextCursor)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(pageJson)
+}
+
+// Returns the movie id of a status path, writing a problem with detail when
+// it is not a valid id.
+func (wh WatchlistHandler) statusMovieID(w http.ResponseWriter, r *http.Request, detail string) (string, bool) {
+	movieID, err := utils.GetMovieIDFromStatusPath(r.URL.Path)
+
+	if err != nil {
+		problem.Write(w, http.StatusNotFound, ErrWatchStatusNotExists)
+		return "", false
+	}
+
+	if err = wh.validate.Var(movieID, "required,uuid"); err != nil {
+		wh.logger.InfoContext(r.Context(), detail, "error", err)
+		problem.WriteFieldErrors(w, detail, problem.FieldErrors(err, "movie_id"))
+		return "", false
+	}
+
+	return movieID, true
+}
+
+// Methods the watchlist routes allow, advertised by Options and 405 responses.
+const watchlistMethods = "GET, PUT, DELETE, OPTIONS"
+
+// Responds with allowed methods.
+func (wh WatchlistHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Methods", watchlistMethods)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (wh WatchlistHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, err := utils.GetMovieIDFromStatusPath(r.URL.Path); err == nil {
+		switch r.Method {
+		case http.MethodGet:
+			wh.getStatus(w, r)
+		case http.MethodPut:
+			wh.putStatus(w, r)
+		case http.MethodDelete:
+			wh.deleteStatus(w, r)
+		case http.MethodOptions:
+			wh.Options(w, r)
+		default:
+			writeMethodNotAllowed(w, r, watchlistMethods)
+		}
+
+		return
+	}
+
+	if _, err := utils.GetUserIDFromWatchlistPath(r.URL.Path); err == nil {
+		switch r.Method {
+		case http.MethodGet:
+			wh.getWatchlist(w, r)
+		case http.MethodOptions:
+			wh.Options(w, r)
+		default:
+			writeMethodNotAllowed(w, r, watchlistMethods)
+		}
+
+		return
+	}
+
+	problem.Write(w, http.StatusNotFound, ErrWatchStatusNotExists)
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"moviepin/auth"
+	"moviepin/db/watchlist"
+	"moviepin/mocks"
+	"moviepin/models"
+)
+
+const (
+	statusPath    = "/movies/6ba7b810-9dad-11d1-80b4-00c04fd430c8/status"
+	watchlistPath = "/users/a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11/watchlist"
+)
+
+func TestPutStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantStatus    string
+		wantWatchedAt time.Time
+	}{
+		{name: "want to watch", body: `{"status":"want_to_watch"}`, wantStatus: models.WatchStatusWantToWatch},
+		{name: "watched on a day", body: `{"status":"watched","watched_at":"2024-03-02T23:30:00-05:00"}`, wantStatus: models.WatchStatusWatched, wantWatchedAt: time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "watched today", body: `{"status":"watched"}`, wantStatus: models.WatchStatusWatched, wantWatchedAt: time.Now().UTC().Truncate(24 * time.Hour)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := NewWatchlistHandler(mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest(http.MethodPut, statusPath, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.putStatus(rr, withUser(req, &mocks.User))
+
+			assertStatusCode(t, rr.Code, http.StatusOK)
+
+			var status models.WatchStatus
+
+			if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil {
+				t.Fatal(err)
+			}
+
+			if status.UserID != mocks.User.ID || status.MovieID != mocks.Movie.ID || status.Status != test.wantStatus {
+				t.Errorf("wrong status, got %+v", status)
+			}
+
+			if test.wantWatchedAt.IsZero() != (status.WatchedAt == nil) || (status.WatchedAt != nil && !status.WatchedAt.Equal(test.wantWatchedAt)) {
+				t.Errorf("got watched at %v, want %v", status.WatchedAt, test.wantWatchedAt)
+			}
+		})
+	}
+}
+
+func TestGetMovieWithStatus(t *testing.T) {
+	handler := NewMoviesHandler(mocks.NewMoviesRepository(), mocks.NewWatchlistRepository(), auth.NewPolicy(), testLogger, testValidate)
+
+	t.Run("authenticated", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/movies/6ba7b810-9dad-11d1-80b4-00c04fd430c8", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getMovie(rr, withUser(req, &mocks.User))
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+
+		var movie models.MovieWithStatus
+
+		if err := json.Unmarshal(rr.Body.Bytes(), &movie); err != nil {
+			t.Fatal(err)
+		}
+
+		if movie.ID != mocks.Movie.ID || movie.WatchStatus == nil || movie.WatchStatus.Status != mocks.WatchStatus.Status {
+			t.Errorf("got %+v, want movie with status %+v", movie, mocks.WatchStatus)
+		}
+
+		if got := rr.Header().Get("Cache-Control"); got != "private" {
+			t.Errorf("got Cache-Control %q, want private", got)
+		}
+
+		if got := rr.Header().Get("Last-Modified"); got != "" {
+			t.Errorf("got Last-Modified %q, want none", got)
+		}
+	})
+
+	t.Run("authenticated without status", func(t *testing.T) {
+		repo := mocks.NewWatchlistRepository()
+		repo.GetStatusError = watchlist.ErrNotExists
+
+		handler := NewMoviesHandler(mocks.NewMoviesRepository(), repo, auth.NewPolicy(), testLogger, testValidate)
+
+		req, err := http.NewRequest(http.MethodGet, "/movies/6ba7b810-9dad-11d1-80b4-00c04fd430c8", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getMovie(rr, withUser(req, &mocks.User))
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+
+		if !strings.Contains(rr.Body.String(), `"watch_status":null`) {
+			t.Errorf("got %s, want null watch_status", rr.Body.String())
+		}
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/movies/6ba7b810-9dad-11d1-80b4-00c04fd430c8", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler.getMovie(rr, req)
+
+		assertStatusCode(t, rr.Code, http.StatusOK)
+
+		if strings.Contains(rr.Body.String(), "watch_status") {
+			t.Errorf("got %s, want no watch_status", rr.Body.String())
+		}
+
+		if got := rr.Header().Get("Vary"); got != "Authorization" {
+			t.Errorf("got Vary %q, want Authorization", got)
+		}
+	})
+}
+
+func TestWatchlistServeHTTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		user   *models.User
+		setup  func(repo *mocks.WatchlistRepository)
+		want   int
+	}{
+		{name: "get status", method: http.MethodGet, path: statusPath, user: &mocks.User, want: http.StatusOK},
+		{name: "get status without user", method: http.MethodGet, path: statusPath, want: http.StatusUnauthorized},
+		{name: "get status not set", method: http.MethodGet, path: statusPath, user: &mocks.User, setup: func(repo *mocks.WatchlistRepository) { repo.GetStatusError = watchlist.ErrNotExists }, want: http.StatusNotFound},
+		{name: "get status invalid movie", method: http.MethodGet, path: "/movies/1/status", user: &mocks.User, want: http.StatusBadRequest},
+		{name: "put status", method: http.MethodPut, path: statusPath, body: `{"status":"watching"}`, user: &mocks.User, want: http.StatusOK},
+		{name: "put status without user", method: http.MethodPut, path: statusPath, body: `{"status":"watching"}`, want: http.StatusUnauthorized},
+		{name: "put unknown status", method: http.MethodPut, path: statusPath, body: `{"status":"maybe"}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "put date when not watched", method: http.MethodPut, path: statusPath, body: `{"status":"watching","watched_at":"2024-03-02T00:00:00Z"}`, user: &mocks.User, want: http.StatusBadRequest},
+		{name: "put status missing movie", method: http.MethodPut, path: statusPath, body: `{"status":"watching"}`, user: &mocks.User, setup: func(repo *mocks.WatchlistRepository) { repo.SetStatusError = watchlist.ErrMovieNotExists }, want: http.StatusNotFound},
+		{name: "put status error", method: http.MethodPut, path: statusPath, body: `{"status":"watching"}`, user: &mocks.User, setup: func(repo *mocks.WatchlistRepository) { repo.SetStatusError = errors.New("error") }, want: http.StatusInternalServerError},
+		{name: "delete status", method: http.MethodDelete, path: statusPath, user: &mocks.User, want: http.StatusNoContent},
+		{name: "delete status not set", method: http.MethodDelete, path: statusPath, user: &mocks.User, setup: func(repo *mocks.WatchlistRepository) { repo.DeleteStatusError = watchlist.ErrNotExists }, want: http.StatusNotFound},
+		{name: "post status", method: http.MethodPost, path: statusPath, user: &mocks.User, want: http.StatusMethodNotAllowed},
+		{name: "get watchlist", method: http.MethodGet, path: watchlistPath + "?status=want_to_watch&limit=10", user: &mocks.User, want: http.StatusOK},
+		{name: "get watchlist of someone else", method: http.MethodGet, path: watchlistPath, user: &mocks.Editor, want: http.StatusForbidden},
+		{name: "get watchlist without user", method: http.MethodGet, path: watchlistPath, want: http.StatusUnauthorized},
+		{name: "get watchlist unknown status", method: http.MethodGet, path: watchlistPath + "?status=maybe", user: &mocks.User, want: http.StatusBadRequest},
+		{name: "get watchlist invalid limit", method: http.MethodGet, path: watchlistPath + "?limit=0", user: &mocks.User, want: http.StatusBadRequest},
+		{name: "get watchlist invalid cursor", method: http.MethodGet, path: watchlistPath + "?cursor=x", user: &mocks.User, setup: func(repo *mocks.WatchlistRepository) { repo.GetWatchlistPageError = watchlist.ErrInvalidCursor }, want: http.StatusBadRequest},
+		{name: "delete watchlist", method: http.MethodDelete, path: watchlistPath, user: &mocks.User, want: http.StatusMethodNotAllowed},
+		{name: "options", method: http.MethodOptions, path: watchlistPath, want: http.StatusNoContent},
+		{name: "unknown path", method: http.MethodGet, path: "/users/1", user: &mocks.User, want: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := mocks.NewWatchlistRepository()
+
+			if test.setup != nil {
+				test.setup(&repo)
+			}
+
+			handler := NewWatchlistHandler(repo, auth.NewPolicy(), testLogger, testValidate)
+
+			req, err := http.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.user != nil {
+				req = withUser(req, test.user)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assertStatusCode(t, rr.Code, test.want)
+
+			if test.want == http.StatusMethodNotAllowed {
+				assertAllow(t, rr, watchlistMethods)
+			}
+		})
+	}
+}
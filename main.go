@@ -1,15 +1,76 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"moviepin/app"
+	"moviepin/config"
+	"moviepin/db"
+	"moviepin/db/users"
 	"moviepin/middleware"
 	"moviepin/routes"
-	"net/http"
+	"moviepin/utils"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	mux := routes.NewServeMux()
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
 
-	loggedMux := middleware.Logger(mux)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 
-	http.ListenAndServe(":4545", loggedMux)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger := utils.NewLogger(os.Stdout, cfg.Log.Format)
+
+	if err = run(cfg, logger); err != nil {
+		logger.Error("server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// Runs the server until SIGINT or SIGTERM, returns error when it fails to
+// start or to stop cleanly.
+func run(cfg config.Config, logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	database, err := db.Open(cfg.Database)
+
+	if err != nil {
+		return err
+	}
+
+	// Closed only after requests drained, so none lose their connection.
+	defer database.Close()
+
+	server := app.New(cfg, database, logger, utils.NewValidator())
+
+	mux := routes.NewServeMux(server)
+
+	authMux := middleware.Auth(mux, users.NewUser(server.DB), server.Logger)
+
+	timeoutMux := middleware.Timeout(authMux, cfg.Server.RequestTimeout)
+
+	loggedMux := middleware.Logger(timeoutMux, server.Logger)
+
+	listener, err := net.Listen("tcp", cfg.Server.Addr)
+
+	if err != nil {
+		return err
+	}
+
+	server.Logger.Info("listening", "addr", listener.Addr().String())
+
+	return server.Serve(ctx, listener, loggedMux)
 }
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"moviepin/auth"
+	"moviepin/db/users"
+	"moviepin/problem"
+)
+
+// Resolves the bearer token of a request to its user and stores the user in
+// the request context. Requests without a token pass through anonymously,
+// requests with an invalid or expired token are rejected.
+func Auth(handler http.Handler, db users.UsersRepository, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+
+		if header == "" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, found := strings.Cut(header, " ")
+
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+			problem.Write(w, http.StatusUnauthorized, "malformed authorization header")
+			return
+		}
+
+		user, err := db.GetSessionUser(r.Context(), auth.HashToken(token))
+
+		if err == users.ErrSessionNotExists {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			problem.Write(w, http.StatusUnauthorized, "invalid or expired token")
+			return
+		}
+
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to authenticate", "error", err)
+			problem.Write(w, problem.FailureStatus(r.Context(), err), "failed to authenticate")
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), user)))
+	})
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"moviepin/auth"
+	"moviepin/db/users"
+	"moviepin/mocks"
+	"moviepin/utils"
+)
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		repoErr  error
+		want     int
+		wantUser bool
+	}{
+		{"anonymous", "", nil, http.StatusOK, false},
+		{"valid token", "Bearer token", nil, http.StatusOK, true},
+		{"lowercase scheme", "bearer token", nil, http.StatusOK, true},
+		{"wrong scheme", "Basic dXNlcjpwYXNz", nil, http.StatusUnauthorized, false},
+		{"missing token", "Bearer ", nil, http.StatusUnauthorized, false},
+		{"expired token", "Bearer token", users.ErrSessionNotExists, http.StatusUnauthorized, false},
+		{"repository error", "Bearer token", errors.New("error"), http.StatusInternalServerError, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := mocks.NewUsersRepository()
+			repo.GetSessionUserError = test.repoErr
+
+			var gotUser bool
+
+			handler := Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, gotUser = auth.UserFromContext(r.Context())
+			}), repo, utils.NewLogger(io.Discard, utils.LogFormatText))
+
+			req, err := http.NewRequest("GET", "/movies", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != test.want {
+				t.Errorf("wrong status code, got %d want %d", rr.Code, test.want)
+			}
+
+			if gotUser != test.wantUser {
+				t.Errorf("got user in context %v, want %v", gotUser, test.wantUser)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import "net/http"
+
+// Sets Cache-Control to value on successful GET and HEAD responses, leaving
+// errors uncached. Handlers can still set their own value.
+func CacheControl(handler http.Handler, value string) http.Handler {
+	if value == "" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		handler.ServeHTTP(&cacheControlWriter{ResponseWriter: w, value: value}, r)
+	})
+}
+
+type cacheControlWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (cw *cacheControlWriter) WriteHeader(status int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+
+		header := cw.Header()
+
+		if (status == http.StatusOK || status == http.StatusNotModified) && header.Get("Cache-Control") == "" {
+			header.Set("Cache-Control", cw.value)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cacheControlWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	return cw.ResponseWriter.Write(b)
+}
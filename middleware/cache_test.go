@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheControl(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+		want   string
+	}{
+		{"ok", "GET", http.StatusOK, "max-age=60"},
+		{"not modified", "GET", http.StatusNotModified, "max-age=60"},
+		{"error", "GET", http.StatusNotFound, ""},
+		{"write", "PUT", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CacheControl(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+				}
+
+				w.Write(nil)
+			}), "max-age=60")
+
+			req, err := http.NewRequest(tt.method, "/movies", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if got := rr.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("wrong Cache-Control, got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
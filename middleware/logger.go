@@ -1,18 +1,116 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"moviepin/utils"
 )
 
-func Logger(handler http.Handler) http.Handler {
+// Header carrying the ID of a request, both ways.
+const RequestIDHeader = "X-Request-ID"
+
+// Longest request ID accepted from clients, longer ones are replaced.
+const maxRequestIDLength = 128
+
+// Tags every request with an ID and logs one line per request with its
+// status, response size and duration. The ID comes from the X-Request-ID
+// header when the client sent a usable one, it is echoed in the response and
+// stored in the request context, so handler logs carry it too.
+func Logger(handler http.Handler, logger *slog.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		handler.ServeHTTP(w, r)
-		end := time.Since(start)
 
-		utils.Logger.Printf("[%s] %s %s", r.Method, r.URL.Path, end.String())
+		id := r.Header.Get(RequestIDHeader)
+
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := utils.NewRequestIDContext(r.Context(), id)
+
+		sw := &statusWriter{ResponseWriter: w}
+
+		handler.ServeHTTP(sw, r.WithContext(ctx))
+
+		logger.InfoContext(ctx, "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.Status(),
+			"bytes", sw.bytes,
+			"duration", time.Since(start),
+		)
 	})
 }
+
+// Returns whether a client sent request ID is short and printable, so it is
+// safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns a random 128 bit request ID in hex.
+func newRequestID() string {
+	b := make([]byte, 16)
+
+	// Never fails, see crypto/rand.Read.
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// Records the status and number of body bytes of a response.
+type statusWriter struct {
+	http.ResponseWriter
+
+	status int
+	bytes  int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += n
+
+	return n, err
+}
+
+// Returns the status sent, 200 when the handler wrote nothing.
+func (sw *statusWriter) Status() int {
+	if sw.status == 0 {
+		return http.StatusOK
+	}
+
+	return sw.status
+}
+
+// Lets http.ResponseController reach the underlying writer, to flush it.
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
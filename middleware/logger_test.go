@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"moviepin/utils"
+)
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{"propagates request id", "abc-123", false},
+		{"generates missing request id", "", true},
+		{"replaces unprintable request id", "abc\n123", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			logger := utils.NewLogger(&out, utils.LogFormatJSON)
+
+			handler := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger.ErrorContext(r.Context(), "failed")
+				w.WriteHeader(http.StatusTeapot)
+				w.Write([]byte("short and stout"))
+			}), logger)
+
+			req, err := http.NewRequest("GET", "/movies", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			id := rr.Header().Get(RequestIDHeader)
+
+			if tt.generated && (id == "" || id == tt.requestID) {
+				t.Errorf("request id was not generated, got %q", id)
+			}
+
+			if !tt.generated && id != tt.requestID {
+				t.Errorf("wrong request id, got %q want %q", id, tt.requestID)
+			}
+
+			decoder := json.NewDecoder(&out)
+
+			var handlerRecord, accessRecord map[string]any
+
+			if err := decoder.Decode(&handlerRecord); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := decoder.Decode(&accessRecord); err != nil {
+				t.Fatal(err)
+			}
+
+			if handlerRecord["request_id"] != id {
+				t.Errorf("handler record has wrong request id, got %v want %v", handlerRecord["request_id"], id)
+			}
+
+			if accessRecord["request_id"] != id {
+				t.Errorf("access record has wrong request id, got %v want %v", accessRecord["request_id"], id)
+			}
+
+			if accessRecord["status"] != float64(http.StatusTeapot) {
+				t.Errorf("wrong status, got %v want %v", accessRecord["status"], http.StatusTeapot)
+			}
+
+			if accessRecord["bytes"] != float64(len("short and stout")) {
+				t.Errorf("wrong bytes, got %v want %v", accessRecord["bytes"], len("short and stout"))
+			}
+		})
+	}
+
+	t.Run("implicit ok", func(t *testing.T) {
+		var out bytes.Buffer
+
+		handler := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), utils.NewLogger(&out, utils.LogFormatJSON))
+
+		req, err := http.NewRequest("GET", "/movies", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		var record map[string]any
+
+		if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+
+		if record["status"] != float64(http.StatusOK) || record["bytes"] != float64(0) {
+			t.Errorf("wrong record, got %v", record)
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	var deadline time.Time
+	var ok bool
+
+	handler := Timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok = r.Context().Deadline()
+	}), time.Minute)
+
+	req, err := http.NewRequest("GET", "/movies", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !ok {
+		t.Fatal("request context has no deadline")
+	}
+
+	if remaining := time.Until(deadline); remaining <= 0 || remaining > time.Minute {
+		t.Errorf("wrong deadline, %v remaining", remaining)
+	}
+}
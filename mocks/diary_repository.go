@@ -0,0 +1,94 @@
+// Mock for the diary repository interface.
+package mocks
+
+import (
+	"context"
+	"moviepin/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	DiaryEntry = models.DiaryEntry{
+		ID:        uuid.MustParse("f5dd0166-6b5e-4ef8-bb6d-6bb9bd380a16"),
+		UserID:    User.ID,
+		MovieID:   Movie.ID,
+		WatchedOn: time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC),
+		Rewatch:   true,
+		Notes:     "Still holds up",
+		CreatedAt: time.Date(2024, time.March, 2, 21, 40, 0, 0, time.UTC),
+		Movie:     &Movie,
+	}
+
+	DiaryStats = models.DiaryStats{
+		FilmsPerYear: []*models.YearStats{{Year: 2024, Films: 1, Entries: 1, Rewatches: 1}},
+		TopGenres:    []*models.FilmCount{{Name: Movie.Genre, Films: 1}},
+		TopDirectors: []*models.FilmCount{{Name: Movie.Director, Films: 1}},
+	}
+)
+
+// DiaryRepository is a mock for the diary repository interface.
+type DiaryRepository struct {
+	GetEntriesError  error
+	GetEntryError    error
+	AddEntryError    error
+	DeleteEntryError error
+	GetStatsError    error
+}
+
+// NewDiaryRepository returns a new instance of the diary repository mock.
+func NewDiaryRepository() DiaryRepository {
+	return DiaryRepository{}
+}
+
+// GetEntries returns the entries of a user watched in a period.
+func (m DiaryRepository) GetEntries(ctx context.Context, userID string, from time.Time, to time.Time) ([]*models.DiaryEntry, error) {
+	if m.GetEntriesError != nil {
+		return nil, m.GetEntriesError
+	}
+
+	entry := DiaryEntry
+
+	return []*models.DiaryEntry{&entry}, nil
+}
+
+// GetEntry returns an entry by its id.
+func (m DiaryRepository) GetEntry(ctx context.Context, userID string, entryID string) (*models.DiaryEntry, error) {
+	if m.GetEntryError != nil {
+		return nil, m.GetEntryError
+	}
+
+	entry := DiaryEntry
+
+	return &entry, nil
+}
+
+// AddEntry adds an entry to the diary.
+func (m DiaryRepository) AddEntry(ctx context.Context, entry models.DiaryEntry) error {
+	if m.AddEntryError != nil {
+		return m.AddEntryError
+	}
+
+	return nil
+}
+
+// DeleteEntry removes an entry from the diary.
+func (m DiaryRepository) DeleteEntry(ctx context.Context, userID string, entryID string) error {
+	if m.DeleteEntryError != nil {
+		return m.DeleteEntryError
+	}
+
+	return nil
+}
+
+// GetStats returns the sample stats.
+func (m DiaryRepository) GetStats(ctx context.Context, userID string, year int, limit int) (*models.DiaryStats, error) {
+	if m.GetStatsError != nil {
+		return nil, m.GetStatsError
+	}
+
+	stats := DiaryStats
+
+	return &stats, nil
+}
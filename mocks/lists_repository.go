@@ -0,0 +1,158 @@
+// Mock for the lists repository interface.
+package mocks
+
+import (
+	"context"
+	"moviepin/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	List = models.List{
+		ID:          uuid.MustParse("d3bbef66-6f3c-4ef8-bb6d-6bb9bd380a14"),
+		UserID:      User.ID,
+		Title:       "Rainy day",
+		Description: "Slow burning thrillers",
+		Visibility:  models.ListVisibilityPublic,
+		Slug:        "rainy-day",
+		CreatedAt:   time.Date(2024, time.February, 3, 8, 20, 52, 0, time.UTC),
+		UpdatedAt:   time.Date(2024, time.February, 3, 8, 20, 52, 0, time.UTC),
+	}
+
+	ListItem = models.ListItem{
+		ID:       uuid.MustParse("e4ccf055-5a4d-4ef8-bb6d-6bb9bd380a15"),
+		ListID:   List.ID,
+		MovieID:  Movie.ID,
+		Position: 0,
+		Movie:    &Movie,
+	}
+)
+
+// ListsRepository is a mock for the lists repository interface.
+type ListsRepository struct {
+	GetListsError       error
+	GetListError        error
+	GetListBySlugError  error
+	GetListItemsError   error
+	AddListError        error
+	UpdateListError     error
+	DeleteListError     error
+	AddListItemError    error
+	DeleteListItemError error
+	MoveListItemError   error
+	SetListOrderError   error
+}
+
+// NewListsRepository returns a new instance of the lists repository mock.
+func NewListsRepository() ListsRepository {
+	return ListsRepository{}
+}
+
+// GetLists returns a slice of the lists of a user.
+func (m ListsRepository) GetLists(ctx context.Context, userID string, publicOnly bool) ([]*models.List, error) {
+	if m.GetListsError != nil {
+		return nil, m.GetListsError
+	}
+
+	list := List
+
+	return []*models.List{&list}, nil
+}
+
+// GetList returns a list by its id.
+func (m ListsRepository) GetList(ctx context.Context, listID string) (*models.List, error) {
+	if m.GetListError != nil {
+		return nil, m.GetListError
+	}
+
+	list := List
+
+	return &list, nil
+}
+
+// GetListBySlug returns a list by the name of its owner and its slug.
+func (m ListsRepository) GetListBySlug(ctx context.Context, username string, slug string) (*models.List, error) {
+	if m.GetListBySlugError != nil {
+		return nil, m.GetListBySlugError
+	}
+
+	list := List
+
+	return &list, nil
+}
+
+// GetListItems returns the items of a list.
+func (m ListsRepository) GetListItems(ctx context.Context, listID string) ([]*models.ListItem, error) {
+	if m.GetListItemsError != nil {
+		return nil, m.GetListItemsError
+	}
+
+	item := ListItem
+
+	return []*models.ListItem{&item}, nil
+}
+
+// AddList adds a list to the database.
+func (m ListsRepository) AddList(ctx context.Context, list models.List) error {
+	if m.AddListError != nil {
+		return m.AddListError
+	}
+
+	return nil
+}
+
+// UpdateList updates a list in the database.
+func (m ListsRepository) UpdateList(ctx context.Context, list models.List) error {
+	if m.UpdateListError != nil {
+		return m.UpdateListError
+	}
+
+	return nil
+}
+
+// DeleteList deletes a list from the database.
+func (m ListsRepository) DeleteList(ctx context.Context, listID string) error {
+	if m.DeleteListError != nil {
+		return m.DeleteListError
+	}
+
+	return nil
+}
+
+// AddListItem appends an item to a list and returns its position.
+func (m ListsRepository) AddListItem(ctx context.Context, item models.ListItem) (int, error) {
+	if m.AddListItemError != nil {
+		return 0, m.AddListItemError
+	}
+
+	return 1, nil
+}
+
+// DeleteListItem removes an item from a list.
+func (m ListsRepository) DeleteListItem(ctx context.Context, listID string, itemID string) error {
+	if m.DeleteListItemError != nil {
+		return m.DeleteListItemError
+	}
+
+	return nil
+}
+
+// MoveListItem moves an item of a list and returns its new position.
+func (m ListsRepository) MoveListItem(ctx context.Context, listID string, itemID string, move models.ListItemMove) (int, error) {
+	if m.MoveListItemError != nil {
+		return 0, m.MoveListItemError
+	}
+
+	return 0, nil
+}
+
+// SetListOrder reorders the items of a list.
+func (m ListsRepository) SetListOrder(ctx context.Context, listID string, itemIDs []uuid.UUID) error {
+	if m.SetListOrderError != nil {
+		return m.SetListOrderError
+	}
+
+	return nil
+}
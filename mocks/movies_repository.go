@@ -2,6 +2,8 @@
 package mocks
 
 import (
+	"context"
+	"moviepin/db/movies"
 	"moviepin/models"
 	"time"
 
@@ -16,6 +18,8 @@ var (
 		Genre:       "Drama",
 		Director:    "Frank Darabont",
 		Description: "Prisoners",
+		CreatedAt:   time.Date(2024, time.February, 3, 8, 20, 52, 0, time.UTC),
+		UpdatedAt:   time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
 	}
 
 	MovieReview = models.MovieReview{
@@ -27,17 +31,40 @@ var (
 		Description: Movie.Description,
 		Rating:      3.5,
 	}
+
+	MovieSuggestion = models.MovieSuggestion{
+		ID:    Movie.ID,
+		Title: Movie.Title,
+		Year:  Movie.ReleaseDate.Year(),
+	}
+
+	MovieSearchResult = models.MovieSearchResult{
+		Movie: Movie,
+		Score: 0.6,
+		Highlights: models.MovieHighlights{
+			Title:       "The <mark>Shawshank</mark> Redemption",
+			Director:    Movie.Director,
+			Description: Movie.Description,
+		},
+	}
 )
 
+// Version the mock repository stores movies at.
+const MovieVersion = 1
+
 // MoviesRepository is a mock for the movies repository interface.
 type MoviesRepository struct {
-	GetMoviesError      error
-	GetMovieError       error
-	AddMovieError       error
-	UpdateMovieError    error
-	DeleteMovieError    error
-	ReplaceMoviesError  error
-	GetMovieRatingError error
+	GetMoviesError          error
+	GetMoviesPageError      error
+	GetMovieError           error
+	AddMovieError           error
+	AddMoviesError          error
+	UpdateMovieError        error
+	DeleteMovieError        error
+	ReplaceMoviesError      error
+	GetMovieRatingError     error
+	SearchMoviesError       error
+	AutocompleteMoviesError error
 }
 
 // NewMoviesRepository returns a new instance of the movies repository mock.
@@ -46,16 +73,19 @@ func NewMoviesRepository() MoviesRepository {
 }
 
 // GetMovie returns a movie by its id.
-func (m MoviesRepository) GetMovie(id string) (*models.Movie, error) {
+func (m MoviesRepository) GetMovie(ctx context.Context, id string) (*models.Movie, error) {
 	if m.GetMovieError != nil {
 		return nil, m.GetMovieError
 	}
 
-	return &Movie, nil
+	movie := Movie
+	movie.Version = MovieVersion
+
+	return &movie, nil
 }
 
 // GetMovies returns a slice of all movies present.
-func (m MoviesRepository) GetMovies() ([]*models.Movie, error) {
+func (m MoviesRepository) GetMovies(ctx context.Context) ([]*models.Movie, error) {
 	if m.GetMoviesError != nil {
 		return nil, m.GetMoviesError
 	}
@@ -63,26 +93,58 @@ func (m MoviesRepository) GetMovies() ([]*models.Movie, error) {
 	return []*models.Movie{&Movie}, nil
 }
 
-// AddMovie adds a movie to the database.
-func (m MoviesRepository) AddMovie(movie models.Movie) error {
+// GetMoviesPage returns a single page holding the mock movie.
+func (m MoviesRepository) GetMoviesPage(ctx context.Context, query movies.MoviesQuery) (*models.MoviesPage, error) {
+	if m.GetMoviesPageError != nil {
+		return nil, m.GetMoviesPageError
+	}
+
+	return &models.MoviesPage{Movies: []*models.Movie{&Movie}}, nil
+}
+
+// AddMovie adds a movie to the database, generating its id when missing.
+func (m MoviesRepository) AddMovie(ctx context.Context, movie models.Movie) (uuid.UUID, error) {
 	if m.AddMovieError != nil {
-		return m.AddMovieError
+		return uuid.Nil, m.AddMovieError
 	}
 
-	return nil
+	if movie.ID == uuid.Nil {
+		return uuid.New(), nil
+	}
+
+	return movie.ID, nil
+}
+
+// AddMovies adds movies to the database, generating missing ids.
+func (m MoviesRepository) AddMovies(ctx context.Context, movies []models.Movie) ([]uuid.UUID, error) {
+	if m.AddMoviesError != nil {
+		return nil, m.AddMoviesError
+	}
+
+	ids := make([]uuid.UUID, len(movies))
+
+	for i, movie := range movies {
+		ids[i] = movie.ID
+
+		if ids[i] == uuid.Nil {
+			ids[i] = uuid.New()
+		}
+	}
+
+	return ids, nil
 }
 
 // UpdateMovie updates a movie in the database.
-func (m MoviesRepository) UpdateMovie(id string, movie models.Movie) error {
+func (m MoviesRepository) UpdateMovie(ctx context.Context, id string, movie models.Movie) (int, error) {
 	if m.UpdateMovieError != nil {
-		return m.UpdateMovieError
+		return 0, m.UpdateMovieError
 	}
 
-	return nil
+	return MovieVersion + 1, nil
 }
 
 // DeleteMovie deletes a movie from the database.
-func (m MoviesRepository) DeleteMovie(id string) error {
+func (m MoviesRepository) DeleteMovie(ctx context.Context, id string, version int) error {
 	if m.DeleteMovieError != nil {
 		return m.DeleteMovieError
 	}
@@ -91,7 +153,7 @@ func (m MoviesRepository) DeleteMovie(id string) error {
 }
 
 // ReplaceMovies replaces all movies in the database.
-func (m MoviesRepository) ReplaceMovies(movies []*models.Movie) error {
+func (m MoviesRepository) ReplaceMovies(ctx context.Context, movies []*models.Movie) error {
 	if m.ReplaceMoviesError != nil {
 		return m.ReplaceMoviesError
 	}
@@ -100,10 +162,28 @@ func (m MoviesRepository) ReplaceMovies(movies []*models.Movie) error {
 }
 
 // GetMovieRating returns a movie rating by its id.
-func (m MoviesRepository) GetMovieRating(id string) (*models.MovieReview, error) {
+func (m MoviesRepository) GetMovieRating(ctx context.Context, id string) (*models.MovieReview, error) {
 	if m.GetMovieRatingError != nil {
 		return nil, m.GetMovieRatingError
 	}
 
 	return &MovieReview, nil
 }
+
+// SearchMovies returns the mock search result.
+func (m MoviesRepository) SearchMovies(ctx context.Context, query string, limit int) ([]*models.MovieSearchResult, error) {
+	if m.SearchMoviesError != nil {
+		return nil, m.SearchMoviesError
+	}
+
+	return []*models.MovieSearchResult{&MovieSearchResult}, nil
+}
+
+// AutocompleteMovies returns the mock suggestion.
+func (m MoviesRepository) AutocompleteMovies(ctx context.Context, prefix string, limit int) ([]*models.MovieSuggestion, error) {
+	if m.AutocompleteMoviesError != nil {
+		return nil, m.AutocompleteMoviesError
+	}
+
+	return []*models.MovieSuggestion{&MovieSuggestion}, nil
+}
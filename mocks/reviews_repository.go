@@ -0,0 +1,85 @@
+// Mock for the reviews repository interface.
+package mocks
+
+import (
+	"context"
+	"moviepin/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	Review = models.Review{
+		ID:         uuid.MustParse("7c9e6679-7425-40de-944b-e07fc1f90ae7"),
+		UserID:     uuid.MustParse("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"),
+		MovieID:    Movie.ID,
+		Rating:     4,
+		ReviewText: "Hope is a good thing",
+		CreatedAt:  time.Date(2024, time.February, 3, 8, 20, 52, 0, time.UTC),
+		UpdatedAt:  time.Date(2024, time.February, 3, 8, 20, 52, 0, time.UTC),
+	}
+)
+
+// ReviewsRepository is a mock for the reviews repository interface.
+type ReviewsRepository struct {
+	GetReviewsError   error
+	GetReviewError    error
+	AddReviewError    error
+	UpdateReviewError error
+	DeleteReviewError error
+}
+
+// NewReviewsRepository returns a new instance of the reviews repository mock.
+func NewReviewsRepository() ReviewsRepository {
+	return ReviewsRepository{}
+}
+
+// GetReviews returns a slice of all reviews of a movie.
+func (m ReviewsRepository) GetReviews(ctx context.Context, movieID string) ([]*models.Review, error) {
+	if m.GetReviewsError != nil {
+		return nil, m.GetReviewsError
+	}
+
+	review := Review
+
+	return []*models.Review{&review}, nil
+}
+
+// GetReview returns a review by its id.
+func (m ReviewsRepository) GetReview(ctx context.Context, movieID string, reviewID string) (*models.Review, error) {
+	if m.GetReviewError != nil {
+		return nil, m.GetReviewError
+	}
+
+	review := Review
+
+	return &review, nil
+}
+
+// AddReview adds a review to the database.
+func (m ReviewsRepository) AddReview(ctx context.Context, review models.Review) error {
+	if m.AddReviewError != nil {
+		return m.AddReviewError
+	}
+
+	return nil
+}
+
+// UpdateReview updates a review in the database.
+func (m ReviewsRepository) UpdateReview(ctx context.Context, movieID string, reviewID string, review models.Review) error {
+	if m.UpdateReviewError != nil {
+		return m.UpdateReviewError
+	}
+
+	return nil
+}
+
+// DeleteReview deletes a review from the database.
+func (m ReviewsRepository) DeleteReview(ctx context.Context, movieID string, reviewID string) error {
+	if m.DeleteReviewError != nil {
+		return m.DeleteReviewError
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+// Mock for the users repository interface.
+package mocks
+
+import (
+	"context"
+	"moviepin/models"
+
+	"github.com/google/uuid"
+)
+
+// Password is the plain text password of the mock user.
+const Password = "password123"
+
+var (
+	User = models.User{
+		ID:           uuid.MustParse("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"),
+		Username:     "dummyuser1",
+		Email:        "dummy@email.com",
+		Role:         "member",
+		PasswordHash: "$2a$10$a6DWLlrz/A/gFikdK8kjzuVA4jVCYgPSkkfBDsw6r3snA.tghGX5K",
+	}
+
+	Editor = models.User{
+		ID:       uuid.MustParse("b1ffcd88-8d1a-4ef8-bb6d-6bb9bd380a12"),
+		Username: "dummyeditor",
+		Email:    "editor@email.com",
+		Role:     "editor",
+	}
+
+	Admin = models.User{
+		ID:       uuid.MustParse("c2aade77-7e2b-4ef8-bb6d-6bb9bd380a13"),
+		Username: "dummyadmin",
+		Email:    "admin@email.com",
+		Role:     "admin",
+	}
+)
+
+// UsersRepository is a mock for the users repository interface.
+type UsersRepository struct {
+	AddUserError           error
+	GetUserByUsernameError error
+	AddSessionError        error
+	GetSessionUserError    error
+}
+
+// NewUsersRepository returns a new instance of the users repository mock.
+func NewUsersRepository() UsersRepository {
+	return UsersRepository{}
+}
+
+// AddUser adds a user to the database.
+func (m UsersRepository) AddUser(ctx context.Context, user models.User) error {
+	if m.AddUserError != nil {
+		return m.AddUserError
+	}
+
+	return nil
+}
+
+// GetUserByUsername returns a user by its username.
+func (m UsersRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if m.GetUserByUsernameError != nil {
+		return nil, m.GetUserByUsernameError
+	}
+
+	user := User
+
+	return &user, nil
+}
+
+// AddSession adds a session to the database.
+func (m UsersRepository) AddSession(ctx context.Context, session models.Session) error {
+	if m.AddSessionError != nil {
+		return m.AddSessionError
+	}
+
+	return nil
+}
+
+// GetSessionUser returns the user owning a session.
+func (m UsersRepository) GetSessionUser(ctx context.Context, tokenHash string) (*models.User, error) {
+	if m.GetSessionUserError != nil {
+		return nil, m.GetSessionUserError
+	}
+
+	user := User
+
+	return &user, nil
+}
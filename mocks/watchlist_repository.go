@@ -0,0 +1,76 @@
+// Mock for the watchlist repository interface.
+package mocks
+
+import (
+	"context"
+	"moviepin/db/watchlist"
+	"moviepin/models"
+	"time"
+)
+
+var (
+	watchedAt = time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC)
+
+	WatchStatus = models.WatchStatus{
+		UserID:    User.ID,
+		MovieID:   Movie.ID,
+		Status:    models.WatchStatusWatched,
+		WatchedAt: &watchedAt,
+		UpdatedAt: time.Date(2024, time.March, 2, 21, 40, 0, 0, time.UTC),
+	}
+)
+
+// WatchlistRepository is a mock for the watchlist repository interface.
+type WatchlistRepository struct {
+	GetStatusError        error
+	SetStatusError        error
+	DeleteStatusError     error
+	GetWatchlistPageError error
+}
+
+// NewWatchlistRepository returns a new instance of the watchlist repository mock.
+func NewWatchlistRepository() WatchlistRepository {
+	return WatchlistRepository{}
+}
+
+// GetStatus returns the status a user gave a movie.
+func (m WatchlistRepository) GetStatus(ctx context.Context, userID string, movieID string) (*models.WatchStatus, error) {
+	if m.GetStatusError != nil {
+		return nil, m.GetStatusError
+	}
+
+	status := WatchStatus
+
+	return &status, nil
+}
+
+// SetStatus sets the status a user gives a movie.
+func (m WatchlistRepository) SetStatus(ctx context.Context, status models.WatchStatus) error {
+	if m.SetStatusError != nil {
+		return m.SetStatusError
+	}
+
+	return nil
+}
+
+// DeleteStatus removes the status a user gave a movie.
+func (m WatchlistRepository) DeleteStatus(ctx context.Context, userID string, movieID string) error {
+	if m.DeleteStatusError != nil {
+		return m.DeleteStatusError
+	}
+
+	return nil
+}
+
+// GetWatchlistPage returns a page holding the sample status and its movie.
+func (m WatchlistRepository) GetWatchlistPage(ctx context.Context, userID string, query watchlist.WatchlistQuery) (*models.WatchlistPage, error) {
+	if m.GetWatchlistPageError != nil {
+		return nil, m.GetWatchlistPageError
+	}
+
+	status := WatchStatus
+	movie := Movie
+	status.Movie = &movie
+
+	return &models.WatchlistPage{Items: []*models.WatchStatus{&status}}, nil
+}
@@ -7,12 +7,43 @@ import (
 )
 
 type Movie struct {
-	ID          uuid.UUID `json:"id" validate:"required,uuid"`
+	ID          uuid.UUID `json:"id" validate:"omitempty,uuid"`
 	Title       string    `json:"title" validate:"required"`
 	ReleaseDate time.Time `json:"release_date" validate:"required"`
 	Genre       string    `json:"genre" validate:"required"`
 	Director    string    `json:"director" validate:"required"`
 	Description string    `json:"description" validate:"required"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Incremented on every update, sent to clients as the ETag.
+	Version int `json:"-"`
+}
+
+type MoviesPage struct {
+	Movies     []*Movie `json:"movies"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+type MovieSearchResult struct {
+	Movie
+	Score      float32         `json:"score"`
+	Highlights MovieHighlights `json:"highlights"`
+}
+
+// Fields of a movie as HTML, escaped, with matched terms wrapped in <mark>
+// tags.
+type MovieHighlights struct {
+	Title       string `json:"title"`
+	Director    string `json:"director"`
+	Description string `json:"description"`
+}
+
+type MovieSuggestion struct {
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title"`
+	Year  int       `json:"year,omitempty"`
 }
 
 type MovieReview struct {
@@ -29,8 +60,168 @@ type Review struct {
 	ID         uuid.UUID `json:"id" validate:"required,uuid"`
 	UserID     uuid.UUID `json:"user_id" validate:"required,uuid"`
 	MovieID    uuid.UUID `json:"movie_id" validate:"required,uuid"`
-	Rating     float32   `json:"rating" validate:"required,lte=5,gte=0"`
+	Rating     int       `json:"rating" validate:"required,min=1,max=5"`
 	ReviewText string    `json:"review_text" validate:"required,lte=500"`
 	CreatedAt  time.Time `json:"created_at" validate:"required"`
 	UpdatedAt  time.Time `json:"updated_at" validate:"required"`
 }
+
+type User struct {
+	ID           uuid.UUID `json:"id" validate:"required,uuid"`
+	Username     string    `json:"username" validate:"required"`
+	Email        string    `json:"email" validate:"required,email"`
+	Role         string    `json:"role" validate:"required,oneof=admin editor member"`
+	PasswordHash string    `json:"-"`
+}
+
+type Registration struct {
+	Username string `json:"username" validate:"required,alphanum,min=3,max=32"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type Credentials struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type Session struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Who can read a list besides its owner.
+const (
+	// Nobody else.
+	ListVisibilityPrivate = "private"
+
+	// Anyone with its link, it is not shown with the lists of its owner.
+	ListVisibilityUnlisted = "unlisted"
+
+	// Anyone, it is shown with the lists of its owner.
+	ListVisibilityPublic = "public"
+)
+
+type List struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Title       string    `json:"title" validate:"required,max=100"`
+	Description string    `json:"description" validate:"max=1000"`
+	Visibility  string    `json:"visibility" validate:"required,oneof=private unlisted public"`
+
+	// Names the list in its shareable path, unique among the lists of its
+	// owner.
+	Slug string `json:"slug" validate:"required,max=100,slug"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// A list with its movies, in the order of their positions.
+type ListDetails struct {
+	List
+	Items []*ListItem `json:"items"`
+}
+
+type ListItem struct {
+	ID      uuid.UUID `json:"id"`
+	ListID  uuid.UUID `json:"list_id"`
+	MovieID uuid.UUID `json:"movie_id" validate:"required"`
+
+	// Place of the movie in the list, starting at 0.
+	Position int `json:"position"`
+
+	Movie *Movie `json:"movie,omitempty"`
+}
+
+// Where to move a list item, exactly one of the fields is set.
+type ListItemMove struct {
+	// Item to put the moved one right before.
+	Before *uuid.UUID `json:"before,omitempty"`
+
+	// Item to put the moved one right after.
+	After *uuid.UUID `json:"after,omitempty"`
+
+	// Position to put the moved one at, starting at 0.
+	Index *int `json:"index,omitempty"`
+}
+
+// Watch statuses a user can give a movie.
+const (
+	WatchStatusWantToWatch = "want_to_watch"
+	WatchStatusWatching    = "watching"
+	WatchStatusWatched     = "watched"
+)
+
+// What a user is doing about a movie, apart from reviewing it.
+type WatchStatus struct {
+	UserID  uuid.UUID `json:"user_id"`
+	MovieID uuid.UUID `json:"movie_id"`
+	Status  string    `json:"status" validate:"required,oneof=want_to_watch watching watched"`
+
+	// Day the movie was watched on, set only when its status is watched.
+	WatchedAt *time.Time `json:"watched_at,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Movie *Movie `json:"movie,omitempty"`
+}
+
+type WatchlistPage struct {
+	Items      []*WatchStatus `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// A movie with the watch status the caller gave it, null when none.
+type MovieWithStatus struct {
+	Movie
+	WatchStatus *WatchStatus `json:"watch_status"`
+}
+
+// A viewing of a movie logged in the diary of a user.
+type DiaryEntry struct {
+	ID       uuid.UUID  `json:"id"`
+	UserID   uuid.UUID  `json:"user_id"`
+	MovieID  uuid.UUID  `json:"movie_id" validate:"required"`
+	ReviewID *uuid.UUID `json:"review_id,omitempty"`
+
+	// Day the movie was watched on.
+	WatchedOn time.Time `json:"watched_on" validate:"required"`
+
+	Rewatch   bool      `json:"rewatch"`
+	Notes     string    `json:"notes" validate:"max=2000"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Movie *Movie `json:"movie,omitempty"`
+}
+
+// Figures computed over the diary of a user.
+type DiaryStats struct {
+	FilmsPerYear []*YearStats `json:"films_per_year"`
+	TopGenres    []*FilmCount `json:"top_genres"`
+	TopDirectors []*FilmCount `json:"top_directors"`
+}
+
+// Viewings logged in a year.
+type YearStats struct {
+	Year int `json:"year"`
+
+	// Number of different movies watched.
+	Films int `json:"films"`
+
+	Entries   int `json:"entries"`
+	Rewatches int `json:"rewatches"`
+}
+
+// Number of different movies watched with a genre or director.
+type FilmCount struct {
+	Name  string `json:"name"`
+	Films int    `json:"films"`
+}
+
+type Token struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
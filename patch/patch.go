@@ -0,0 +1,454 @@
+// This package applies JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902)
+// documents to JSON documents.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergeContentType is the media type of JSON Merge Patch documents.
+	MergeContentType = "application/merge-patch+json"
+
+	// JSONContentType is the media type of JSON Patch documents.
+	JSONContentType = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch is returned when the patch document is malformed.
+	ErrInvalidPatch = errors.New("invalid patch")
+
+	// ErrInvalidPointer is returned when a path is not a JSON Pointer.
+	ErrInvalidPointer = errors.New("invalid JSON pointer")
+
+	// ErrPathNotFound is returned when a path points at nothing.
+	ErrPathNotFound = errors.New("path does not exist")
+
+	// ErrTestFailed is returned when the value of a test operation does not
+	// match the document.
+	ErrTestFailed = errors.New("test failed")
+)
+
+// Operation is a single operation of a JSON Patch.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// OperationError tells which operation of a JSON Patch failed.
+type OperationError struct {
+	// Position of the operation in the patch.
+	Index int
+
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %d (%s %s): %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// Returns doc with the JSON Merge Patch applied. Members of the patch set to
+// null are removed from doc, objects are merged recursively and every other
+// value replaces the one in doc.
+func Merge(doc []byte, patch []byte) ([]byte, error) {
+	var target any
+
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	var mergePatch any
+
+	if err := json.Unmarshal(patch, &mergePatch); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(merge(target, mergePatch))
+}
+
+func merge(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+
+	if !ok {
+		targetObject = map[string]any{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+
+		targetObject[key] = merge(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// Returns doc with the operations of the JSON Patch applied in order. Fails
+// with an *OperationError on the first operation that cannot be applied, doc
+// is never partially patched.
+func Apply(doc []byte, patch []byte) ([]byte, error) {
+	var target any
+
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	var operations []Operation
+
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	for i, operation := range operations {
+		var err error
+
+		target, err = apply(target, operation)
+
+		if err != nil {
+			return nil, &OperationError{Index: i, Op: operation.Op, Path: operation.Path, Err: err}
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func apply(doc any, operation Operation) (any, error) {
+	path, err := parsePointer(operation.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		value, err := operationValue(operation)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch operation.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		}
+
+		current, err := get(doc, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.DeepEqual(current, value) {
+			return nil, ErrTestFailed
+		}
+
+		return doc, nil
+	case "remove":
+		return remove(doc, path)
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+
+		value, err := get(doc, from)
+
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+
+		if operation.Op == "copy" {
+			return add(doc, path, clone(value))
+		}
+
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidPatch)
+		}
+
+		doc, err = remove(doc, from)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return add(doc, path, value)
+	case "":
+		return nil, fmt.Errorf("%w: op is required", ErrInvalidPatch)
+	}
+
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, operation.Op)
+}
+
+func operationValue(operation Operation) (any, error) {
+	if operation.Value == nil {
+		return nil, fmt.Errorf("%w: value is required", ErrInvalidPatch)
+	}
+
+	var value any
+
+	if err := json.Unmarshal(operation.Value, &value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return value, nil
+}
+
+// Returns reference tokens of a JSON Pointer (RFC 6901), an empty pointer
+// refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: %q must start with /", ErrInvalidPointer, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("%w: %q has an invalid escape", ErrInvalidPointer, pointer)
+			}
+		}
+
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func isPrefix(prefix []string, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns the value path points at.
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		var err error
+
+		doc, err = child(doc, token)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+// Returns the member or element named token of an object or array.
+func child(container any, token string) (any, error) {
+	switch container := container.(type) {
+	case map[string]any:
+		value, ok := container[token]
+
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+
+		return value, nil
+	case []any:
+		i, err := index(token, len(container)-1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return container[i], nil
+	}
+
+	return nil, ErrPathNotFound
+}
+
+// Returns the array index token refers to, which must not be above max.
+func index(token string, max int) (int, error) {
+	if token == "-" {
+		return 0, fmt.Errorf("%w: - refers past the end of the array", ErrPathNotFound)
+	}
+
+	// Leading zeros and signs are not allowed.
+	if token == "" || (len(token) > 1 && token[0] == '0') || token[0] < '0' || token[0] > '9' {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalidPointer, token)
+	}
+
+	i, err := strconv.Atoi(token)
+
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalidPointer, token)
+	}
+
+	if i > max {
+		return 0, ErrPathNotFound
+	}
+
+	return i, nil
+}
+
+// Calls fn with the parent of the value path points at and the last token of
+// path, replacing the parent in doc with the container fn returns.
+func modify(doc any, path []string, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	value, err := child(doc, path[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = modify(value, path[1:], fn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch container := doc.(type) {
+	case map[string]any:
+		container[path[0]] = value
+	case []any:
+		i, _ := index(path[0], len(container)-1)
+		container[i] = value
+	}
+
+	return doc, nil
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return modify(doc, path, func(parent any, token string) (any, error) {
+		switch parent := parent.(type) {
+		case map[string]any:
+			parent[token] = value
+			return parent, nil
+		case []any:
+			i := len(parent)
+
+			if token != "-" {
+				var err error
+
+				i, err = index(token, len(parent))
+
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			parent = append(parent, nil)
+			copy(parent[i+1:], parent[i:])
+			parent[i] = value
+
+			return parent, nil
+		}
+
+		return nil, ErrPathNotFound
+	})
+}
+
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+
+	return modify(doc, path, func(parent any, token string) (any, error) {
+		if _, err := child(parent, token); err != nil {
+			return nil, err
+		}
+
+		switch parent := parent.(type) {
+		case map[string]any:
+			delete(parent, token)
+			return parent, nil
+		case []any:
+			i, _ := index(token, len(parent)-1)
+			return append(parent[:i], parent[i+1:]...), nil
+		}
+
+		return nil, ErrPathNotFound
+	})
+}
+
+func replace(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return modify(doc, path, func(parent any, token string) (any, error) {
+		if _, err := child(parent, token); err != nil {
+			return nil, err
+		}
+
+		switch parent := parent.(type) {
+		case map[string]any:
+			parent[token] = value
+		case []any:
+			i, _ := index(token, len(parent)-1)
+			parent[i] = value
+		}
+
+		return parent, nil
+	})
+}
+
+// Returns a deep copy of a decoded JSON value.
+func clone(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		object := make(map[string]any, len(value))
+
+		for key, member := range value {
+			object[key] = clone(member)
+		}
+
+		return object
+	case []any:
+		array := make([]any, len(value))
+
+		for i, element := range value {
+			array[i] = clone(element)
+		}
+
+		return array
+	}
+
+	return value
+}
@@ -0,0 +1,188 @@
+// This package renders errors as RFC 7807 problem details.
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// ContentType of every problem response.
+	ContentType = "application/problem+json"
+
+	// Type of problems that are fully described by their status code.
+	TypeBlank = "about:blank"
+
+	// Type of problems caused by invalid request input.
+	TypeValidation = "/problems/validation-error"
+)
+
+// Details is the body of a problem response.
+type Details struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single field of the request is invalid.
+type FieldError struct {
+	// JSON name of the field, like "release_date" or "[2].title" for an
+	// element of a batch.
+	Field string `json:"field"`
+
+	// Validation rule the field broke, like "required" or "uuid".
+	Rule string `json:"rule"`
+
+	// Parameter of the rule, like "5" for "lte=5".
+	Param string `json:"param,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// Writes a problem with status, detail explains what went wrong.
+func Write(w http.ResponseWriter, status int, detail string) {
+	write(w, Details{
+		Type:   TypeBlank,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+}
+
+// Writes a 400 problem for invalid input, listing each field err reports as
+// invalid. Errors that are not about fields are appended to detail instead.
+func WriteInvalid(w http.ResponseWriter, detail string, err error) {
+	fieldErrors := FieldErrors(err, "")
+
+	if len(fieldErrors) == 0 && err != nil {
+		detail += ": " + err.Error()
+	}
+
+	WriteFieldErrors(w, detail, fieldErrors)
+}
+
+// Writes a 400 problem listing field errors.
+func WriteFieldErrors(w http.ResponseWriter, detail string, fieldErrors []FieldError) {
+	write(w, Details{
+		Type:   TypeValidation,
+		Title:  "Invalid request",
+		Status: http.StatusBadRequest,
+		Detail: detail,
+		Errors: fieldErrors,
+	})
+}
+
+func write(w http.ResponseWriter, details Details) {
+	body, err := json.Marshal(details)
+
+	if err != nil {
+		http.Error(w, details.Detail, details.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(details.Status)
+	w.Write(body)
+}
+
+// Returns the fields err reports as invalid, named relative to prefix. Knows
+// about validation errors and JSON type mismatches, returns nil for others.
+// Validation of a single variable reports the prefix itself as the field.
+func FieldErrors(err error, prefix string) []FieldError {
+	var fieldErrors []FieldError
+
+	var validationErrors validator.ValidationErrors
+
+	if errors.As(err, &validationErrors) {
+		for _, fe := range validationErrors {
+			// Drop the struct name the namespace starts with.
+			_, field, _ := strings.Cut(fe.Namespace(), ".")
+
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   join(prefix, field),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: message(fe),
+			})
+		}
+	}
+
+	var typeError *json.UnmarshalTypeError
+
+	if errors.As(err, &typeError) {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   join(prefix, typeError.Field),
+			Rule:    "type",
+			Param:   typeError.Type.String(),
+			Message: fmt.Sprintf("must be %s, not %s", typeError.Type, typeError.Value),
+		})
+	}
+
+	return fieldErrors
+}
+
+// Returns field name nested under prefix.
+func join(prefix string, field string) string {
+	switch {
+	case prefix == "":
+		return field
+	case field == "":
+		return prefix
+	case strings.HasPrefix(field, "["):
+		return prefix + field
+	default:
+		return prefix + "." + field
+	}
+}
+
+// Returns human readable description of a broken validation rule.
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "uuid":
+		return "must be a UUID"
+	case "email":
+		return "must be an email address"
+	case "alphanum":
+		return "must only contain letters and digits"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "min":
+		return "must be at least " + fe.Param() + " long"
+	case "max":
+		return "must be at most " + fe.Param() + " long"
+	case "slug":
+		return "must be lowercase letters and digits joined by single dashes"
+	}
+
+	return "failed " + fe.Tag() + " validation"
+}
+
+// Returns the status of a request with ctx that failed with err: 504 when its
+// deadline passed, 503 when it was canceled and 500 otherwise. The database
+// driver does not always return the context error itself, so ctx is checked
+// too.
+func FailureStatus(ctx context.Context, err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,191 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type item struct {
+	Title  string `json:"title" validate:"required"`
+	Rating int    `json:"rating" validate:"gte=1,lte=5"`
+}
+
+func newValidate() *validator.Validate {
+	validate := validator.New()
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("json")
+	})
+
+	return validate
+}
+
+func TestWrite(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	Write(rr, http.StatusNotFound, "movie does not exist")
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("wrong status code, got %d want %d", rr.Code, http.StatusNotFound)
+	}
+
+	if got := rr.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("wrong content type, got %v want %v", got, ContentType)
+	}
+
+	var details Details
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &details); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Details{
+		Type:   TypeBlank,
+		Title:  "Not Found",
+		Status: http.StatusNotFound,
+		Detail: "movie does not exist",
+	}
+
+	if !reflect.DeepEqual(details, want) {
+		t.Errorf("wrong details, got %+v want %+v", details, want)
+	}
+}
+
+func TestWriteInvalid(t *testing.T) {
+	t.Run("validation errors", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		WriteInvalid(rr, "failed to add item", newValidate().Struct(item{Rating: 6}))
+
+		var details Details
+
+		if err := json.Unmarshal(rr.Body.Bytes(), &details); err != nil {
+			t.Fatal(err)
+		}
+
+		if details.Type != TypeValidation || details.Status != http.StatusBadRequest {
+			t.Errorf("wrong details, got %+v", details)
+		}
+
+		if details.Detail != "failed to add item" {
+			t.Errorf("wrong detail, got %v", details.Detail)
+		}
+
+		if len(details.Errors) != 2 {
+			t.Fatalf("wrong number of field errors, got %v want %v", len(details.Errors), 2)
+		}
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		WriteInvalid(rr, "failed to get movies", errors.New("invalid cursor"))
+
+		var details Details
+
+		if err := json.Unmarshal(rr.Body.Bytes(), &details); err != nil {
+			t.Fatal(err)
+		}
+
+		if want := "failed to get movies: invalid cursor"; details.Detail != want {
+			t.Errorf("wrong detail, got %v want %v", details.Detail, want)
+		}
+
+		if len(details.Errors) != 0 {
+			t.Errorf("unexpected field errors %+v", details.Errors)
+		}
+	})
+}
+
+func TestFieldErrors(t *testing.T) {
+	validate := newValidate()
+
+	tests := []struct {
+		name   string
+		err    error
+		prefix string
+		want   []FieldError
+	}{
+		{
+			name: "struct",
+			err:  validate.Struct(item{Title: "Up", Rating: 6}),
+			want: []FieldError{
+				{Field: "rating", Rule: "lte", Param: "5", Message: "must be at most 5"},
+			},
+		},
+		{
+			name:   "batch element",
+			err:    validate.Struct(item{Rating: 3}),
+			prefix: "[2]",
+			want: []FieldError{
+				{Field: "[2].title", Rule: "required", Message: "is required"},
+			},
+		},
+		{
+			name:   "variable",
+			err:    validate.Var("1", "required,uuid"),
+			prefix: "id",
+			want: []FieldError{
+				{Field: "id", Rule: "uuid", Message: "must be a UUID"},
+			},
+		},
+		{
+			name: "type mismatch",
+			err:  json.Unmarshal([]byte(`{"rating":"five"}`), &item{}),
+			want: []FieldError{
+				{Field: "rating", Rule: "type", Param: "int", Message: "must be int, not string"},
+			},
+		},
+		{
+			name: "other error",
+			err:  errors.New("error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FieldErrors(tt.err, tt.prefix)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong field errors, got %+v want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureStatus(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want int
+	}{
+		{"deadline error", context.Background(), fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{"deadline passed", expired, errors.New("pq: canceling statement due to user request"), http.StatusGatewayTimeout},
+		{"canceled", canceled, errors.New("error"), http.StatusServiceUnavailable},
+		{"other error", context.Background(), errors.New("error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FailureStatus(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("wrong status, got %d want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,20 +1,66 @@
 package routes
 
 import (
-	"moviepin/db"
+	"moviepin/app"
+	"moviepin/auth"
+	"moviepin/db/diary"
+	"moviepin/db/lists"
 	"moviepin/db/movies"
+	"moviepin/db/reviews"
+	"moviepin/db/users"
+	"moviepin/db/watchlist"
 	"moviepin/handlers"
+	"moviepin/middleware"
 	"net/http"
 )
 
 // Returns a mux with all routes added.
-func NewServeMux() *http.ServeMux {
+func NewServeMux(app *app.App) *http.ServeMux {
 	mux := http.NewServeMux()
 
-	moviesDB := movies.NewMovie(db.DB)
+	moviesDB := movies.NewMovie(app.DB)
+	reviewsDB := reviews.NewReview(app.DB)
+	usersDB := users.NewUser(app.DB)
+	listsDB := lists.NewList(app.DB)
+	watchlistDB := watchlist.NewWatchlist(app.DB)
+	diaryDB := diary.NewDiary(app.DB)
 
-	mux.Handle("/movies", handlers.NewMoviesHandler(moviesDB))
-	mux.Handle("/movies/", handlers.NewMoviesHandler(moviesDB))
+	policy := auth.NewPolicy()
+
+	moviesHandler := handlers.NewMoviesHandler(moviesDB, watchlistDB, policy, app.Logger, app.Validate)
+	reviewsHandler := handlers.NewReviewsHandler(moviesDB, reviewsDB, policy, app.Logger, app.Validate)
+	listsHandler := handlers.NewListsHandler(listsDB, moviesDB, policy, app.Logger, app.Validate)
+	watchlistHandler := handlers.NewWatchlistHandler(watchlistDB, policy, app.Logger, app.Validate)
+	diaryHandler := handlers.NewDiaryHandler(diaryDB, reviewsDB, policy, app.Logger, app.Validate)
+
+	mux.Handle("/movies", middleware.CacheControl(moviesHandler, app.Config.Cache.Movies))
+	mux.Handle("/movies/{id}", middleware.CacheControl(moviesHandler, app.Config.Cache.Movie))
+
+	// Search results have no validators to revalidate, so the movie policy
+	// is kept off them.
+	mux.Handle("/movies/search", moviesHandler)
+	mux.Handle("/movies/autocomplete", moviesHandler)
+	mux.Handle("/movies/", moviesHandler)
+
+	mux.Handle("/movies/{id}/reviews", reviewsHandler)
+	mux.Handle("/movies/{id}/reviews/", reviewsHandler)
+
+	mux.Handle("/movies/{id}/status", watchlistHandler)
+
+	mux.Handle("/users/{id}/lists", listsHandler)
+	mux.Handle("/lists/", listsHandler)
+	mux.Handle("/l/{username}/{slug}", listsHandler)
+
+	mux.Handle("/users/{id}/watchlist", watchlistHandler)
+
+	mux.Handle("/users/{id}/diary", diaryHandler)
+	mux.Handle("/users/{id}/diary/", diaryHandler)
+
+	if app.Config.Features.Registration {
+		mux.Handle("/users", handlers.NewUsersHandler(usersDB, app.Logger, app.Validate))
+	}
+
+	mux.Handle("/auth/login", handlers.NewAuthHandler(usersDB, app.Logger, app.Validate))
 
 	return mux
 }
@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"moviepin/app"
+	"moviepin/config"
+	"moviepin/utils"
+
+	_ "github.com/lib/pq"
+)
+
+func TestNewServeMux(t *testing.T) {
+	// Opening does not connect, so no database is needed as long as no
+	// request reaches it.
+	db, err := sql.Open("postgres", config.Default().Database.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	mux := NewServeMux(app.New(config.Default(), db, utils.NewLogger(io.Discard, utils.LogFormatText), utils.NewValidator()))
+
+	paths := []string{
+		"/movies",
+		"/movies/6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"/movies/6ba7b810-9dad-11d1-80b4-00c04fd430c8/reviews",
+		"/movies/6ba7b810-9dad-11d1-80b4-00c04fd430c8/status",
+		"/users",
+		"/users/a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11/lists",
+		"/lists/d3bbef66-6f3c-4ef8-bb6d-6bb9bd380a14/items",
+		"/l/dummyuser1/rainy-day",
+		"/users/a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11/watchlist",
+		"/users/a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11/diary",
+		"/users/a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11/diary/stats",
+		"/auth/login",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			req, err := http.NewRequest("OPTIONS", path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusNoContent {
+				t.Errorf("wrong status code, got %d want %d", rr.Code, http.StatusNoContent)
+			}
+		})
+	}
+}
+
+func TestMovieCacheRoutes(t *testing.T) {
+	db, err := sql.Open("postgres", config.Default().Database.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	mux := NewServeMux(app.New(config.Default(), db, utils.NewLogger(io.Discard, utils.LogFormatText), utils.NewValidator()))
+
+	// Only the movie itself is served with the movie cache policy.
+	tests := map[string]string{
+		"/movies/6ba7b810-9dad-11d1-80b4-00c04fd430c8": "/movies/{id}",
+		"/movies/search":       "/movies/search",
+		"/movies/autocomplete": "/movies/autocomplete",
+	}
+
+	for path, want := range tests {
+		req, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, pattern := mux.Handler(req); pattern != want {
+			t.Errorf("wrong pattern for %v, got %v want %v", path, pattern, want)
+		}
+	}
+}
@@ -1,12 +1,62 @@
 package utils
 
 import (
-	"log"
-	"os"
+	"context"
+	"io"
+	"log/slog"
 )
 
-var Logger *log.Logger
+// Formats NewLogger writes records in.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Returns a logger writing records to w, as JSON objects when format is
+// LogFormatJSON and as key=value lines otherwise. Records logged with a
+// request context carry the request ID.
+func NewLogger(w io.Writer, format string) *slog.Logger {
+	var handler slog.Handler
+
+	if format == LogFormatJSON {
+		handler = slog.NewJSONHandler(w, nil)
+	} else {
+		handler = slog.NewTextHandler(w, nil)
+	}
+
+	return slog.New(requestIDHandler{handler})
+}
+
+type requestIDKey struct{}
+
+// Returns a copy of ctx carrying the ID of the request it belongs to.
+func NewRequestIDContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// Returns the request ID stored in ctx, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// Adds the request ID of the context records are logged with.
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := RequestIDFromContext(ctx); ok {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
 
-func init() {
-	Logger = log.New(os.Stdout, "", log.Ldate|log.Ltime)
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
 }
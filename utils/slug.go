@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Longest slug accepted.
+const MaxSlugLength = 100
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// Reports whether s is a slug: groups of lowercase ASCII letters and digits
+// joined by single dashes.
+func IsSlug(s string) bool {
+	return slugPattern.MatchString(s)
+}
+
+// Returns a slug made of s, at most maxLength long. Letters are lowercased,
+// every run of other characters than ASCII letters and digits becomes one
+// dash. Returns an empty string when s has no such letters or digits.
+func Slugify(s string, maxLength int) string {
+	var b strings.Builder
+
+	dash := false
+
+	for _, c := range strings.ToLower(s) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+
+			b.WriteRune(c)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	slug := b.String()
+
+	if len(slug) > maxLength {
+		slug = strings.TrimRight(slug[:maxLength], "-")
+	}
+
+	return slug
+}
@@ -0,0 +1,50 @@
+package utils
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		s         string
+		maxLength int
+		want      string
+	}{
+		{s: "Rainy day", maxLength: 100, want: "rainy-day"},
+		{s: "  Best of 2024!  ", maxLength: 100, want: "best-of-2024"},
+		{s: "Kurosawa -- Early & Late", maxLength: 100, want: "kurosawa-early-late"},
+		{s: "Amélie and friends", maxLength: 100, want: "am-lie-and-friends"},
+		{s: "Rainy day", maxLength: 6, want: "rainy"},
+		{s: "!!!", maxLength: 100, want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			got := Slugify(test.s, test.maxLength)
+
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+
+			if got != "" && !IsSlug(got) {
+				t.Errorf("%q is not a slug", got)
+			}
+		})
+	}
+}
+
+func TestSlugValidation(t *testing.T) {
+	validate := NewValidator()
+
+	for slug, want := range map[string]bool{
+		"rainy-day":  true,
+		"2024":       true,
+		"Rainy-day":  false,
+		"rainy--day": false,
+		"-rainy":     false,
+		"rainy day":  false,
+		"":           false,
+	} {
+		if got := validate.Var(slug, "slug") == nil; got != want {
+			t.Errorf("slug %q valid %v, want %v", slug, got, want)
+		}
+	}
+}
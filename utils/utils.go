@@ -18,3 +18,116 @@ func GetIDFromPath(path string) (string, error) {
 
 	return matches[1], nil
 }
+
+// Returns movie id and review id from a reviews path. Review id is empty for
+// the reviews collection path.
+func GetReviewIDsFromPath(path string) (string, string, error) {
+	matches := regexp.MustCompile(`/movies/([^/]+)/reviews(?:/([^/]+))?/?$`).FindStringSubmatch(path)
+
+	if len(matches) != 3 {
+		return "", "", ErrInvalidPath
+	}
+
+	return matches[1], matches[2], nil
+}
+
+// Returns user id from a user's lists path.
+func GetUserIDFromListsPath(path string) (string, error) {
+	matches := regexp.MustCompile(`/users/([^/]+)/lists/?$`).FindStringSubmatch(path)
+
+	if len(matches) != 2 {
+		return "", ErrInvalidPath
+	}
+
+	return matches[1], nil
+}
+
+// Returns list id from a list path.
+func GetListIDFromPath(path string) (string, error) {
+	matches := regexp.MustCompile(`/lists/([^/]+)/?$`).FindStringSubmatch(path)
+
+	if len(matches) != 2 {
+		return "", ErrInvalidPath
+	}
+
+	return matches[1], nil
+}
+
+// Returns list id and item id from a list items path. Item id is empty for
+// the items collection path.
+func GetListItemIDsFromPath(path string) (string, string, error) {
+	matches := regexp.MustCompile(`/lists/([^/]+)/items(?:/([^/]+))?/?$`).FindStringSubmatch(path)
+
+	if len(matches) != 3 {
+		return "", "", ErrInvalidPath
+	}
+
+	return matches[1], matches[2], nil
+}
+
+// Returns username and slug from the shareable path of a list.
+func GetListSlugFromPath(path string) (string, string, error) {
+	matches := regexp.MustCompile(`^/l/([^/]+)/([^/]+)/?$`).FindStringSubmatch(path)
+
+	if len(matches) != 3 {
+		return "", "", ErrInvalidPath
+	}
+
+	return matches[1], matches[2], nil
+}
+
+// Returns list id and item id from the path moving a list item.
+func GetListItemIDsFromMovePath(path string) (string, string, error) {
+	matches := regexp.MustCompile(`/lists/([^/]+)/items/([^/]+)/move/?$`).FindStringSubmatch(path)
+
+	if len(matches) != 3 {
+		return "", "", ErrInvalidPath
+	}
+
+	return matches[1], matches[2], nil
+}
+
+// Returns movie id from a movie status path.
+func GetMovieIDFromStatusPath(path string) (string, error) {
+	matches := regexp.MustCompile(`/movies/([^/]+)/status/?$`).FindStringSubmatch(path)
+
+	if len(matches) != 2 {
+		return "", ErrInvalidPath
+	}
+
+	return matches[1], nil
+}
+
+// Returns user id from a user's watchlist path.
+func GetUserIDFromWatchlistPath(path string) (string, error) {
+	matches := regexp.MustCompile(`/users/([^/]+)/watchlist/?$`).FindStringSubmatch(path)
+
+	if len(matches) != 2 {
+		return "", ErrInvalidPath
+	}
+
+	return matches[1], nil
+}
+
+// Returns user id and entry id from a user's diary path. Entry id is empty
+// for the diary collection path.
+func GetDiaryIDsFromPath(path string) (string, string, error) {
+	matches := regexp.MustCompile(`/users/([^/]+)/diary(?:/([^/]+))?/?$`).FindStringSubmatch(path)
+
+	if len(matches) != 3 {
+		return "", "", ErrInvalidPath
+	}
+
+	return matches[1], matches[2], nil
+}
+
+// Returns user id from a user's diary stats path.
+func GetUserIDFromDiaryStatsPath(path string) (string, error) {
+	matches := regexp.MustCompile(`/users/([^/]+)/diary/stats/?$`).FindStringSubmatch(path)
+
+	if len(matches) != 2 {
+		return "", ErrInvalidPath
+	}
+
+	return matches[1], nil
+}
@@ -55,3 +55,220 @@ func TestGetIDFromPath(t *testing.T) {
 		})
 	}
 }
+
+func TestGetReviewIDsFromPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		wantMovieID  string
+		wantReviewID string
+		wantErr      error
+	}{
+		{
+			name:         "collection path",
+			path:         "/movies/123/reviews",
+			wantMovieID:  "123",
+			wantReviewID: "",
+			wantErr:      nil,
+		},
+		{
+			name:         "collection path with trailing slash",
+			path:         "/movies/123/reviews/",
+			wantMovieID:  "123",
+			wantReviewID: "",
+			wantErr:      nil,
+		},
+		{
+			name:         "review path",
+			path:         "/movies/123/reviews/456",
+			wantMovieID:  "123",
+			wantReviewID: "456",
+			wantErr:      nil,
+		},
+		{
+			name:         "review path with trailing slash",
+			path:         "/movies/123/reviews/456/",
+			wantMovieID:  "123",
+			wantReviewID: "456",
+			wantErr:      nil,
+		},
+		{
+			name:         "invalid path with extra segment",
+			path:         "/movies/123/reviews/456/789",
+			wantMovieID:  "",
+			wantReviewID: "",
+			wantErr:      ErrInvalidPath,
+		},
+		{
+			name:         "invalid path without reviews segment",
+			path:         "/movies/123",
+			wantMovieID:  "",
+			wantReviewID: "",
+			wantErr:      ErrInvalidPath,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotMovieID, gotReviewID, err := GetReviewIDsFromPath(test.path)
+
+			if gotMovieID != test.wantMovieID {
+				t.Errorf("got movie id %s, want %s", gotMovieID, test.wantMovieID)
+			}
+
+			if gotReviewID != test.wantReviewID {
+				t.Errorf("got review id %s, want %s", gotReviewID, test.wantReviewID)
+			}
+
+			if err != test.wantErr {
+				t.Errorf("got %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetListPaths(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantUserID string
+		wantListID string
+		wantItemID string
+		isItems    bool
+		isMove     bool
+	}{
+		{path: "/users/1/lists", wantUserID: "1"},
+		{path: "/users/1/lists/", wantUserID: "1"},
+		{path: "/lists/2", wantListID: "2"},
+		{path: "/lists/2/", wantListID: "2"},
+		{path: "/lists/2/items", wantListID: "2", isItems: true},
+		{path: "/lists/2/items/3/", wantListID: "2", wantItemID: "3", isItems: true},
+		{path: "/lists/2/items/3/4"},
+		{path: "/lists/2/items/3/move", wantListID: "2", wantItemID: "3", isMove: true},
+		{path: "/lists/2/items/3/move/", wantListID: "2", wantItemID: "3", isMove: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			userID, _ := GetUserIDFromListsPath(test.path)
+
+			if userID != test.wantUserID {
+				t.Errorf("got user id %q, want %q", userID, test.wantUserID)
+			}
+
+			listID, itemID, err := GetListItemIDsFromPath(test.path)
+
+			if test.isItems && (err != nil || listID != test.wantListID || itemID != test.wantItemID) {
+				t.Errorf("got list id %q and item id %q (%v), want %q and %q", listID, itemID, err, test.wantListID, test.wantItemID)
+			}
+
+			if !test.isItems && err != ErrInvalidPath {
+				t.Errorf("got error %v, want %v", err, ErrInvalidPath)
+			}
+
+			listID, itemID, err = GetListItemIDsFromMovePath(test.path)
+
+			if test.isMove && (err != nil || listID != test.wantListID || itemID != test.wantItemID) {
+				t.Errorf("got list id %q and item id %q (%v) for move, want %q and %q", listID, itemID, err, test.wantListID, test.wantItemID)
+			}
+
+			if !test.isMove && err != ErrInvalidPath {
+				t.Errorf("got error %v for move, want %v", err, ErrInvalidPath)
+			}
+
+			if listID, err := GetListIDFromPath(test.path); !test.isItems && !test.isMove && test.wantListID != "" && (err != nil || listID != test.wantListID) {
+				t.Errorf("got list id %q (%v), want %q", listID, err, test.wantListID)
+			}
+		})
+	}
+}
+
+func TestGetWatchlistPaths(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantMovieID string
+		wantUserID  string
+	}{
+		{path: "/movies/1/status", wantMovieID: "1"},
+		{path: "/movies/1/status/", wantMovieID: "1"},
+		{path: "/users/2/watchlist", wantUserID: "2"},
+		{path: "/users/2/watchlist/", wantUserID: "2"},
+		{path: "/movies/1/status/3"},
+		{path: "/users/2/watchlist/3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			movieID, err := GetMovieIDFromStatusPath(test.path)
+
+			if movieID != test.wantMovieID || (test.wantMovieID == "" && err != ErrInvalidPath) {
+				t.Errorf("got movie id %q (%v), want %q", movieID, err, test.wantMovieID)
+			}
+
+			userID, err := GetUserIDFromWatchlistPath(test.path)
+
+			if userID != test.wantUserID || (test.wantUserID == "" && err != ErrInvalidPath) {
+				t.Errorf("got user id %q (%v), want %q", userID, err, test.wantUserID)
+			}
+		})
+	}
+}
+
+func TestGetDiaryPaths(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantUserID  string
+		wantEntryID string
+		isStats     bool
+		isInvalid   bool
+	}{
+		{path: "/users/1/diary", wantUserID: "1"},
+		{path: "/users/1/diary/", wantUserID: "1"},
+		{path: "/users/1/diary/2", wantUserID: "1", wantEntryID: "2"},
+		{path: "/users/1/diary/stats/", wantUserID: "1", wantEntryID: "stats", isStats: true},
+		{path: "/users/1/diary/2/3", isInvalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			userID, entryID, err := GetDiaryIDsFromPath(test.path)
+
+			if userID != test.wantUserID || entryID != test.wantEntryID || (err == ErrInvalidPath) != test.isInvalid {
+				t.Errorf("got user id %q and entry id %q (%v), want %q and %q", userID, entryID, err, test.wantUserID, test.wantEntryID)
+			}
+
+			userID, err = GetUserIDFromDiaryStatsPath(test.path)
+
+			if test.isStats && (err != nil || userID != test.wantUserID) {
+				t.Errorf("got stats user id %q (%v), want %q", userID, err, test.wantUserID)
+			}
+
+			if !test.isStats && err != ErrInvalidPath {
+				t.Errorf("got error %v, want %v", err, ErrInvalidPath)
+			}
+		})
+	}
+}
+
+func TestGetListSlugFromPath(t *testing.T) {
+	tests := []struct {
+		path         string
+		wantUsername string
+		wantSlug     string
+		wantErr      error
+	}{
+		{path: "/l/dummyuser1/rainy-day", wantUsername: "dummyuser1", wantSlug: "rainy-day"},
+		{path: "/l/dummyuser1/rainy-day/", wantUsername: "dummyuser1", wantSlug: "rainy-day"},
+		{path: "/l/dummyuser1", wantErr: ErrInvalidPath},
+		{path: "/lists/l/dummyuser1/rainy-day", wantErr: ErrInvalidPath},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			username, slug, err := GetListSlugFromPath(test.path)
+
+			if username != test.wantUsername || slug != test.wantSlug || err != test.wantErr {
+				t.Errorf("got %q and %q (%v), want %q and %q (%v)", username, slug, err, test.wantUsername, test.wantSlug, test.wantErr)
+			}
+		})
+	}
+}
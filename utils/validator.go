@@ -1,9 +1,30 @@
 package utils
 
-import "github.com/go-playground/validator/v10"
+import (
+	"reflect"
+	"strings"
 
-var Validate *validator.Validate
+	"github.com/go-playground/validator/v10"
+)
 
-func init() {
-	Validate = validator.New()
+// Returns a validator that reports fields by their JSON names, which are the
+// names clients know. Besides the built in tags it knows slug, see IsSlug.
+func NewValidator() *validator.Validate {
+	validate := validator.New()
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if name == "-" {
+			return ""
+		}
+
+		return name
+	})
+
+	validate.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return IsSlug(fl.Field().String())
+	})
+
+	return validate
 }